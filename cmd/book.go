@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ashpect/ch3ckm8/engine"
+	"github.com/spf13/cobra"
+)
+
+const startFen = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+
+var (
+	bookPath     string
+	bookFen      string
+	bookPgn      string
+	bookOut      string
+	bookMaxPly   int
+	bookMinGames int
+)
+
+// bookCmd represents the book command
+var bookCmd = &cobra.Command{
+	Use:   "book",
+	Short: "Work with Polyglot opening books",
+}
+
+// bookProbeCmd represents the book probe command
+var bookProbeCmd = &cobra.Command{
+	Use:   "probe",
+	Short: "List the book moves for a position",
+	Long: `Probe looks a position up in a Polyglot opening book and lists the book moves
+with their weights. For example:
+
+ch3ckm8 book probe --book book.bin --fen "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1"`,
+	Run: func(cmd *cobra.Command, args []string) {
+		key, moves, err := engine.ProbeBook(bookPath, bookFen)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		fmt.Printf("Key: %016x\n", key)
+		if len(moves) == 0 {
+			fmt.Println("No book moves")
+			return
+		}
+		total := 0
+		for _, m := range moves {
+			total += m.Weight
+		}
+		for _, m := range moves {
+			share := 0.0
+			if total > 0 {
+				share = 100 * float64(m.Weight) / float64(total)
+			}
+			fmt.Printf("%-6s weight %5d  %6.2f%%\n", m.Move, m.Weight, share)
+		}
+	},
+}
+
+// bookBuildCmd represents the book build command
+var bookBuildCmd = &cobra.Command{
+	Use:   "build",
+	Short: "Build a Polyglot book from PGN games",
+	Long: `Build makes a Polyglot opening book from a collection of games. Moves from the
+first --max-ply plies of each game that were played in at least --min-games games
+go into the book, weighted by frequency and score. For example:
+
+ch3ckm8 book build --pgn games.pgn --out book.bin --max-ply 20 --min-games 3`,
+	Run: func(cmd *cobra.Command, args []string) {
+		stats, err := engine.BuildBook(bookPgn, bookOut, bookMaxPly, bookMinGames)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		fmt.Printf("Read %d games (%d without a result, %d with unreadable moves)\n", stats.Games, stats.Skipped, stats.Errors)
+		fmt.Printf("Wrote %d moves in %d positions to %s\n", stats.Entries, stats.Positions, bookOut)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(bookCmd)
+	bookCmd.AddCommand(bookProbeCmd)
+	bookCmd.AddCommand(bookBuildCmd)
+
+	bookCmd.PersistentFlags().StringVar(&bookPath, "book", "book.bin", "Polyglot book file")
+	bookProbeCmd.Flags().StringVar(&bookFen, "fen", startFen, "position to look up")
+
+	bookBuildCmd.Flags().StringVar(&bookPgn, "pgn", "", "PGN file of games to build the book from")
+	bookBuildCmd.Flags().StringVar(&bookOut, "out", "book.bin", "book file to write")
+	bookBuildCmd.Flags().IntVar(&bookMaxPly, "max-ply", 20, "only use the first moves of each game, in plies")
+	bookBuildCmd.Flags().IntVar(&bookMinGames, "min-games", 3, "only keep moves played in at least this many games")
+	bookBuildCmd.MarkFlagRequired("pgn")
+}
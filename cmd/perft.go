@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ashpect/ch3ckm8/engine"
+	"github.com/spf13/cobra"
+)
+
+var (
+	perftFen   string
+	perftDepth int
+	perftSuite bool
+)
+
+// perftCmd represents the perft command
+var perftCmd = &cobra.Command{
+	Use:   "perft",
+	Short: "Count the positions the move generator reaches",
+	Long: `Perft counts the positions reached from a position after --depth plies, split
+by the first move. --suite instead counts a set of test positions and compares
+them with their published counts, up to --depth or as deep as those go. For
+example:
+
+ch3ckm8 perft --depth 4
+ch3ckm8 perft --suite --depth 3`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if perftSuite {
+			failed := 0
+			for _, p := range engine.PerftPositions() {
+				depth := min(perftDepth, len(p.Nodes))
+				nodes := runPerft(p.Fen, depth, false)
+				status := "ok"
+				if nodes != p.Nodes[depth-1] {
+					status = "MISMATCH"
+					failed++
+				}
+				fmt.Printf("%-8s %d %10d %10d  %s\n", status, depth, nodes, p.Nodes[depth-1], p.Fen)
+			}
+			if failed > 0 {
+				fmt.Printf("%d positions differ\n", failed)
+				os.Exit(1)
+			}
+			return
+		}
+		runPerft(perftFen, perftDepth, true)
+	},
+}
+
+// runPerft counts the positions below fen and, if verbose, prints the count for each
+// root move.
+func runPerft(fen string, depth int, verbose bool) (nodes uint64) {
+	start := time.Now()
+	divide, err := engine.Perft(fen, depth)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	elapsed := time.Since(start)
+	for _, d := range divide {
+		nodes += d.Nodes
+		if verbose {
+			fmt.Printf("%s: %d\n", d.Move, d.Nodes)
+		}
+	}
+	if verbose {
+		fmt.Printf("\nNodes: %d\n", nodes)
+		fmt.Printf("Time: %v (%.0f nodes/s)\n", elapsed.Round(time.Millisecond), float64(nodes)/elapsed.Seconds())
+	}
+	return nodes
+}
+
+func init() {
+	rootCmd.AddCommand(perftCmd)
+
+	perftCmd.Flags().StringVar(&perftFen, "fen", startFen, "position to count from")
+	perftCmd.Flags().IntVar(&perftDepth, "depth", 4, "plies to count to")
+	perftCmd.Flags().BoolVar(&perftSuite, "suite", false, "check a set of test positions against their published counts")
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ashpect/ch3ckm8/engine"
+	"github.com/spf13/cobra"
+)
+
+var (
+	pgnPath   string
+	pgnOut    string
+	pgnFormat string
+)
+
+// pgnCmd represents the pgn command
+var pgnCmd = &cobra.Command{
+	Use:   "pgn",
+	Short: "Check and convert PGN files",
+}
+
+// pgnValidateCmd represents the pgn validate command
+var pgnValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Play through the games of a PGN file and report bad moves",
+	Long: `Validate plays every game of a PGN file, variations included, and reports
+the first move of each game that is illegal, ambiguous or unreadable, and results
+the final position contradicts. For example:
+
+ch3ckm8 pgn validate --pgn games.pgn`,
+	Run: func(cmd *cobra.Command, args []string) {
+		reports, err := engine.ValidatePGN(pgnPath)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if printPGNReports(reports) > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+// pgnConvertCmd represents the pgn convert command
+var pgnConvertCmd = &cobra.Command{
+	Use:   "convert",
+	Short: "Rewrite the games of a PGN file in another format",
+	Long: `Convert writes the games of a PGN file that validate to --out, as
+	pgn  PGN export format, with the moves written again in standard SAN
+	uci  one line per game, the arguments of a position command
+	epd  every position of each finished game with its result, for tune
+Games that do not validate are reported and left out. For example:
+
+ch3ckm8 pgn convert --pgn games.pgn --out clean.pgn
+ch3ckm8 pgn convert --pgn games.pgn --out positions.epd --format epd`,
+	Run: func(cmd *cobra.Command, args []string) {
+		reports, err := engine.ConvertPGN(pgnPath, pgnOut, pgnFormat)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		bad := printPGNReports(reports)
+		fmt.Printf("Wrote %d games to %s\n", len(reports)-bad, pgnOut)
+	},
+}
+
+// printPGNReports lists the games with problems and a summary, and returns how many
+// games had problems.
+func printPGNReports(reports []engine.PGNGameReport) (bad int) {
+	for _, r := range reports {
+		if r.Err != nil {
+			fmt.Printf("game %d (%s - %s): %v\n", r.Game, r.White, r.Black, r.Err)
+			bad++
+		}
+	}
+	fmt.Printf("Read %d games, %d with problems\n", len(reports), bad)
+	return bad
+}
+
+func init() {
+	rootCmd.AddCommand(pgnCmd)
+	pgnCmd.AddCommand(pgnValidateCmd)
+	pgnCmd.AddCommand(pgnConvertCmd)
+
+	pgnCmd.PersistentFlags().StringVar(&pgnPath, "pgn", "", "PGN file to read")
+	pgnCmd.MarkPersistentFlagRequired("pgn")
+
+	pgnConvertCmd.Flags().StringVar(&pgnOut, "out", "", "file to write")
+	pgnConvertCmd.Flags().StringVar(&pgnFormat, "format", "pgn", "format to write: pgn, uci or epd")
+	pgnConvertCmd.MarkFlagRequired("out")
+}
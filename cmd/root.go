@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/ashpect/ch3ckm8/engine"
 	"github.com/spf13/cobra"
 )
 
@@ -39,6 +40,8 @@ const colorNone = "\033[0m"
 const colorYellow = "\033[1;33m"
 
 var depth int
+var evalParamsPath string
+var nnuePath string
 
 func init() {
 	// Here you will define your flags and configuration settings.
@@ -46,6 +49,8 @@ func init() {
 	// will be global for your application.
 
 	rootCmd.PersistentFlags().IntVarP(&depth, "depth", "d", 0, "setup depth")
+	rootCmd.PersistentFlags().StringVar(&evalParamsPath, "eval-params", "", "JSON file with evaluation parameters to use instead of the built-in ones")
+	rootCmd.PersistentFlags().StringVar(&nnuePath, "nnue", "", "network weights file to evaluate with instead of the hand-crafted eval")
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
@@ -61,3 +66,29 @@ func init() {
 	fmt.Println()
 	rootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 }
+
+// loadEvalParams makes the engine use the weights given with --eval-params, if any.
+func loadEvalParams() {
+	if evalParamsPath == "" {
+		return
+	}
+	params, err := engine.LoadEvalParams(evalParamsPath)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	engine.SetEvalParams(params)
+}
+
+// loadNetwork makes the engine evaluate with the network given with --nnue, if any.
+func loadNetwork() {
+	if nnuePath == "" {
+		return
+	}
+	net, err := engine.LoadNetwork(nnuePath)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	engine.SetEvaluator(net)
+}
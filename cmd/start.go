@@ -49,5 +49,7 @@ func init() {
 }
 
 func start(depth int) {
+	loadEvalParams()
+	loadNetwork()
 	engine.Uci(engine.Input(), depth)
 }
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ashpect/ch3ckm8/engine"
+	"github.com/spf13/cobra"
+)
+
+var (
+	tbMaterial string
+	tbOut      string
+	tbFiles    string
+	tbFen      string
+)
+
+// tbCmd represents the tb command
+var tbCmd = &cobra.Command{
+	Use:   "tb",
+	Short: "Generate and probe endgame tables",
+}
+
+// tbGenerateCmd represents the tb generate command
+var tbGenerateCmd = &cobra.Command{
+	Use:   "generate",
+	Short: "Generate a distance to mate table by retrograde analysis",
+	Long: `Generate works out the distance to mate of every position with the given
+material, working backwards from the mates. The tables a capture leads to are
+generated along the way. Material without pawns and with up to four pieces is
+supported. For example:
+
+ch3ckm8 tb generate --material KRvK --out krk.tb`,
+	Run: func(cmd *cobra.Command, args []string) {
+		table, err := engine.GenerateRetroTable(tbMaterial)
+		if err == nil {
+			err = table.Save(tbOut)
+		}
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		fmt.Printf("Wrote %s to %s\n", table.Material(), tbOut)
+	},
+}
+
+// tbProbeCmd represents the tb probe command
+var tbProbeCmd = &cobra.Command{
+	Use:   "probe",
+	Short: "Look a position up in generated tables",
+	Long: `Probe looks a position up in tables made by tb generate, given as a list of
+files separated as in PATH. For example:
+
+ch3ckm8 tb probe --tables krk.tb --fen "8/8/8/4k3/8/8/8/R3K3 w - - 0 1"`,
+	Run: func(cmd *cobra.Command, args []string) {
+		wdl, plies, err := engine.ProbeRetroTables(tbFiles, tbFen)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		switch wdl {
+		case engine.WDLWin:
+			fmt.Printf("Win for the side to move, mate in %d plies\n", plies)
+		case engine.WDLLoss:
+			fmt.Printf("Loss for the side to move, mated in %d plies\n", plies)
+		default:
+			fmt.Println("Draw")
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(tbCmd)
+	tbCmd.AddCommand(tbGenerateCmd)
+	tbCmd.AddCommand(tbProbeCmd)
+
+	tbGenerateCmd.Flags().StringVar(&tbMaterial, "material", "", "pieces of each side, e.g. KRvK")
+	tbGenerateCmd.Flags().StringVar(&tbOut, "out", "", "table file to write")
+	tbGenerateCmd.MarkFlagRequired("material")
+	tbGenerateCmd.MarkFlagRequired("out")
+
+	tbProbeCmd.Flags().StringVar(&tbFiles, "tables", "", "table files, separated as in PATH")
+	tbProbeCmd.Flags().StringVar(&tbFen, "fen", "", "position to look up")
+	tbProbeCmd.MarkFlagRequired("tables")
+	tbProbeCmd.MarkFlagRequired("fen")
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ashpect/ch3ckm8/engine"
+	"github.com/spf13/cobra"
+)
+
+var (
+	tuneData   string
+	tuneOut    string
+	tunePasses int
+)
+
+// tuneCmd represents the tune command
+var tuneCmd = &cobra.Command{
+	Use:   "tune",
+	Short: "Tune the evaluation weights on a set of labelled positions",
+	Long: `Tune fits the evaluation weights to quiet positions labelled with the results
+of the games they were taken from, using Texel's method. The positions are read
+from an EPD file, one per line, with the result as a c9 "1-0"; opcode or as a
+bare 1-0, 0-1, 1/2-1/2, [1.0], [0.5] or [0.0]. Tuning starts from the weights
+given with --eval-params, or the built-in ones, and the tuned weights are saved
+to a file that --eval-params can load.
+
+For example:
+
+ch3ckm8 tune --data positions.epd --out params.json`,
+	Run: func(cmd *cobra.Command, args []string) {
+		loadEvalParams()
+		if err := engine.Tune(tuneData, tuneOut, tunePasses); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(tuneCmd)
+
+	tuneCmd.Flags().StringVar(&tuneData, "data", "", "EPD file of labelled positions")
+	tuneCmd.Flags().StringVar(&tuneOut, "out", "params.json", "file to write the tuned weights to")
+	tuneCmd.Flags().IntVar(&tunePasses, "passes", 100, "maximum number of passes over the weights")
+	tuneCmd.MarkFlagRequired("data")
+}
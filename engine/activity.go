@@ -0,0 +1,126 @@
+package engine
+
+import "math/bits"
+
+// evalPieceActivity scores how active each side's pieces are: pseudo-legal mobility,
+// the bishop pair, rooks on open files and the seventh rank, outposts and trapped pieces.
+func (b *Board) evalPieceActivity() (scorePair, scorePair) {
+	return b.pieceActivity(true), b.pieceActivity(false)
+}
+
+func (b *Board) pieceActivity(isWhite bool) scorePair {
+	var score scorePair
+	colour, enemy := colourIndex(isWhite), colourIndex(!isWhite)
+	own := &b.pieces[colour]
+	ownPieces, ownPawns, enemyPawns := b.colours[colour], own[pawnIndex], b.pieces[enemy][pawnIndex]
+	ownKing, enemyKing := own[kingIndex], b.pieces[enemy][kingIndex]
+	knights, bishops, rooks, queens := own[knightIndex], own[bishopIndex], own[rookIndex], own[queenIndex]
+
+	// squares worth counting for mobility: not blocked by our own pieces and
+	// not covered by enemy pawns
+	mobilityArea := ^ownPieces &^ pawnAttacks(enemyPawns, !isWhite)
+	ownPawnAttacks := pawnAttacks(ownPawns, isWhite)
+
+	mobility := func(pieceType PieceType, attacks uint64) int {
+		count := bits.OnesCount64(attacks & mobilityArea)
+		score = score.add(evalParams.Mobility.of(pieceType).scale(count - evalParams.MobilityAverage.of(pieceType)))
+		return count
+	}
+
+	// an outpost is a square on the enemy's half, defended by our pawns, that no enemy
+	// pawn can ever attack
+	isOutpost := func(pos uint64) bool {
+		sq := bits.TrailingZeros64(pos)
+		rank := relativeRank(sq, isWhite)
+		return rank >= 3 && rank <= 5 && pos&ownPawnAttacks != 0 &&
+			passedPawnMasks[colour][sq]&adjacentFileMasks[squareFile(sq)]&enemyPawns == 0
+	}
+
+	for pieces := knights; pieces != 0; pieces &= pieces - 1 {
+		pos := pieces & -pieces
+		mobility(Knight, knightAttacks(pos))
+		if isOutpost(pos) {
+			score = score.add(evalParams.KnightOutpost)
+		}
+	}
+
+	for pieces := bishops; pieces != 0; pieces &= pieces - 1 {
+		pos := pieces & -pieces
+		mobility(Bishop, slidingAttacks(pos, b.allPieces, diagDirs))
+		if isOutpost(pos) {
+			score = score.add(evalParams.BishopOutpost)
+		}
+		if b.isBishopTrapped(pos, isWhite, enemyPawns) {
+			score = score.add(evalParams.TrappedBishop)
+		}
+	}
+	if bishops&darkSquares != 0 && bishops&^darkSquares != 0 {
+		score = score.add(evalParams.BishopPair)
+	}
+
+	for pieces := rooks; pieces != 0; pieces &= pieces - 1 {
+		pos := pieces & -pieces
+		sq := bits.TrailingZeros64(pos)
+		file := fileMasks[squareFile(sq)]
+		count := mobility(Rook, slidingAttacks(pos, b.allPieces, straightDirs))
+
+		if file&ownPawns == 0 {
+			if file&enemyPawns == 0 {
+				score = score.add(evalParams.RookOpenFile)
+			} else {
+				score = score.add(evalParams.RookSemiOpenFile)
+			}
+		}
+
+		// the seventh rank matters while it holds enemy pawns or cuts off the enemy king
+		if relativeRank(sq, isWhite) == 6 {
+			seventh := bottomEdge << uint(8*squareRank(sq))
+			eighth := seventh << 8
+			if !isWhite {
+				eighth = seventh >> 8
+			}
+			if seventh&enemyPawns != 0 || eighth&enemyKing != 0 {
+				score = score.add(evalParams.RookOnSeventh)
+			}
+		}
+
+		// a rook boxed in on the back rank by its own uncastled king
+		if count <= 3 && relativeRank(sq, isWhite) == 0 && ownKing&(bottomEdge<<uint(8*squareRank(sq))) != 0 {
+			kingFile, rookFile := squareFile(bits.TrailingZeros64(ownKing)), squareFile(sq)
+			if (kingFile < 4 && rookFile < kingFile) || (kingFile >= 4 && rookFile > kingFile) {
+				score = score.add(evalParams.TrappedRook)
+			}
+		}
+	}
+
+	for pieces := queens; pieces != 0; pieces &= pieces - 1 {
+		pos := pieces & -pieces
+		mobility(Queen, slidingAttacks(pos, b.allPieces, allDirs))
+	}
+
+	return score
+}
+
+// isBishopTrapped recognises a bishop that took a pawn on a7/h7 (a2/h2 for black) and
+// was shut in by a pawn move to b6/g6 (b3/g3).
+func (b *Board) isBishopTrapped(pos uint64, isWhite bool, enemyPawns uint64) bool {
+	sq := bits.TrailingZeros64(pos)
+	if relativeRank(sq, isWhite) != 6 {
+		return false
+	}
+	var blocker uint64
+	switch squareFile(sq) {
+	case 0:
+		blocker = pos >> 1 // b-file, one file towards h
+	case 7:
+		blocker = pos << 1 // g-file
+	default:
+		return false
+	}
+	if isWhite {
+		blocker >>= 8
+	} else {
+		blocker <<= 8
+	}
+	return blocker&enemyPawns != 0
+}
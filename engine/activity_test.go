@@ -0,0 +1,70 @@
+package engine
+
+import "testing"
+
+func TestPieceActivity(t *testing.T) {
+	tests := []struct {
+		name          string
+		better, worse string
+	}{
+		{"knight mobility",
+			"4k3/8/8/8/4N3/8/8/4K3 w - - 0 1",
+			"4k3/8/8/8/8/8/8/N3K3 w - - 0 1"},
+		{"rook on an open file",
+			"4k3/p6p/8/8/8/8/P6P/3RK3 w - - 0 1",
+			"4k3/p2p3p/8/8/8/8/P2P3P/3RK3 w - - 0 1"},
+		{"rook on a semi-open file",
+			"4k3/p2p3p/8/8/8/8/P6P/3RK3 w - - 0 1",
+			"4k3/p2p3p/8/8/8/8/P2P3P/3RK3 w - - 0 1"},
+		{"rook on the seventh",
+			"6k1/p3pppp/8/8/8/8/3R4/4K3 w - - 0 1",
+			"3R2k1/p3pppp/8/8/8/8/8/4K3 w - - 0 1"},
+		{"knight outpost",
+			"4k3/p1p3p1/8/4N3/3P4/8/8/4K3 w - - 0 1",
+			"4k3/p1p2pp1/8/4N3/3P4/8/8/4K3 w - - 0 1"},
+		{"trapped bishop",
+			"4k3/Bp6/8/8/8/8/8/4K3 w - - 0 1",
+			"4k3/B7/1p6/8/8/8/8/4K3 w - - 0 1"},
+		{"rook boxed in by its king",
+			"4k3/8/8/8/8/8/6PP/5RK1 w - - 0 1",
+			"4k3/8/8/8/8/8/6PP/5K1R w - - 0 1"},
+	}
+	phase := totalPhase / 2
+	for _, tt := range tests {
+		better, worse := testBoard(tt.better).pieceActivity(true), testBoard(tt.worse).pieceActivity(true)
+		if taper(worse.mg, worse.eg, phase) >= taper(better.mg, better.eg, phase) {
+			t.Errorf("%s: activity %v, not worse than %v", tt.name, worse, better)
+		}
+	}
+}
+
+func TestBishopPair(t *testing.T) {
+	// the bishops on c1 and b1 see seven squares each, so only the pair differs
+	pair := testBoard("4k3/8/8/8/8/8/8/2B1KB2 w - - 0 1").pieceActivity(true)
+	same := testBoard("4k3/8/8/8/8/8/8/1B2KB2 w - - 0 1").pieceActivity(true)
+	if got := pair.sub(same); got != evalParams.BishopPair {
+		t.Errorf("bishop pair worth %v, want %v", got, evalParams.BishopPair)
+	}
+}
+
+func TestIsBishopTrapped(t *testing.T) {
+	tests := []struct {
+		fen     string
+		square  string
+		isWhite bool
+		want    bool
+	}{
+		{"4k3/Bp6/1p6/8/8/8/8/4K3 w - - 0 1", "a7", true, true},
+		{"4k3/B7/8/1p6/8/8/8/4K3 w - - 0 1", "a7", true, false},
+		{"4k3/6pB/6p1/8/8/8/8/4K3 w - - 0 1", "h7", true, true},
+		{"4k3/8/8/8/8/1P6/b7/4K3 b - - 0 1", "a2", false, true},
+		{"4k3/8/1p6/B7/8/8/8/4K3 w - - 0 1", "a5", true, false},
+	}
+	for _, tt := range tests {
+		b := testBoard(tt.fen)
+		enemyPawns := b.pieces[colourIndex(!tt.isWhite)][pawnIndex]
+		if got := b.isBishopTrapped(b.notationToPos(tt.square), tt.isWhite, enemyPawns); got != tt.want {
+			t.Errorf("%s: bishop on %s trapped = %v, want %v", tt.fen, tt.square, got, tt.want)
+		}
+	}
+}
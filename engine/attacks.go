@@ -0,0 +1,180 @@
+package engine
+
+import "math/bits"
+
+// Attack tables, filled in once at startup. Knights, kings and pawns attack the same
+// squares from a square whatever else is on the board, so their attacks are looked up
+// by square. Rook and bishop attacks depend on the pieces in their way: the squares
+// that could block them are masked out of the occupancy, multiplied by a magic number
+// that gathers them into the top bits, and the result indexes the attacks for that
+// square. Tables have a 65th entry, for square 64, so that looking up an empty
+// bitboard finds no attacks.
+var (
+	knightAttackTable [65]uint64
+	kingAttackTable   [65]uint64
+	pawnAttackTable   [2][65]uint64 // by colour, white first
+
+	rookMagics   [65]magic
+	bishopMagics [65]magic
+)
+
+// magic finds the attacks of a slider on one square.
+type magic struct {
+	mask    uint64 // squares whose occupancy can block the slider
+	magic   uint64
+	shift   uint
+	attacks []uint64
+}
+
+func (m *magic) index(occupied uint64) uint64 {
+	return ((occupied & m.mask) * m.magic) >> m.shift
+}
+
+// The magic numbers for each square, in the board's square order. They were found by
+// trying random sparse numbers until one sent every occupancy of the mask to a slot
+// holding the right attacks.
+var rookMagicNumbers = [64]uint64{
+	0x198000802010c000, 0x034001c010002000, 0x2080200080081000, 0x8280100080480004,
+	0x0280040002080080, 0x0500020100084400, 0x0180008009000200, 0x4100021040802100,
+	0x0000800028904002, 0x0000c00050002008, 0xb082002200104080, 0x8081000810010020,
+	0x00ca002006000810, 0x0001000401000208, 0x410d000200144100, 0x0003000040820900,
+	0x0080004020004000, 0x8010810020400100, 0x0000110020010040, 0x0040808008001000,
+	0x0100808004000800, 0x2080808002000400, 0x0860840002104108, 0x02052200049c0041,
+	0x0018810200204200, 0x4420002040100040, 0x0000100080200080, 0x0001880280100084,
+	0x0100040080800800, 0x900c004040020100, 0x0000100400016288, 0x2000004200041881,
+	0x0280002000400040, 0x022080210100400a, 0x0010002401200800, 0x0000800800801001,
+	0x0080800800800402, 0x4000042008011040, 0x2008080204001001, 0x08104080d2000f04,
+	0x2840184020808002, 0x001008412000c008, 0x1280201200820040, 0x084811c2000a0020,
+	0x0102080101050010, 0x1088020004008080, 0x0000d00822040011, 0x0004004400820001,
+	0x00a08000c10ee100, 0x0000200040008080, 0x0084441083220200, 0x4000201001000900,
+	0x0841110108000500, 0x0022001004080200, 0x6014010810420400, 0x004004090c4a8200,
+	0x0003004080002053, 0x0020400411088021, 0x0000a10810c08202, 0x0000189000252101,
+	0x0002002008900502, 0x8032002c0148101a, 0x000008021100d014, 0xc300040908408822,
+}
+
+var bishopMagicNumbers = [64]uint64{
+	0x0420010408004040, 0x0420048400802001, 0x5062021400220008, 0x2004440880000002,
+	0x0c14242034000000, 0x0000822020810003, 0x4c94120144203192, 0x00c8820100a00408,
+	0x0408d20212080210, 0x0e02220841240488, 0x002148020c420400, 0x4040082048400810,
+	0x2400040420892004, 0x040a220a30051010, 0x8008084108201021, 0x0210110c030c02c3,
+	0x0c08404210040080, 0x0020803031020088, 0x0a0400c084001040, 0x000084a802004042,
+	0x2410800400a04040, 0x100a000088090800, 0x0044040042025084, 0x000100304c008428,
+	0x000240044a081840, 0x0004100242508101, 0x2088040320410020, 0xc198101048004090,
+	0x00088402a0802000, 0x0010090000844103, 0x02820c004080c800, 0x8224110800208a00,
+	0x2a24020844411000, 0x0040a82000080200, 0x3020203008080080, 0x1000040400080120,
+	0x2010020080380484, 0x00110121000a0040, 0x11040104000a2080, 0x0c08020028804320,
+	0x0d860aa020008405, 0x0006115008044200, 0x828d201250000802, 0x0800020122044400,
+	0x0000381104020040, 0x0010111009009020, 0x1010440504000251, 0x003850c08080a200,
+	0x00006a1010084040, 0x0802008404821030, 0x0041004604900000, 0x0a00308042020009,
+	0x80000240104101a4, 0x0500082008009200, 0x0010020801042080, 0x1421020206002c10,
+	0x3a02060202014400, 0x2222042424040400, 0x8204029300513000, 0x0800000000208808,
+	0x0101404020520c80, 0x01408840112a1220, 0x100340100a0a2860, 0x00042962040c0180,
+}
+
+func init() {
+	for sq := 0; sq < 64; sq++ {
+		pos := uint64(1) << sq
+		knightAttackTable[sq] = knightJumps(pos)
+		kingAttackTable[sq] = rayAttacks(pos, ^uint64(0), allDirs)
+		pawnAttackTable[0][sq] = pawnAttacks(pos, true)
+		pawnAttackTable[1][sq] = pawnAttacks(pos, false)
+
+		// a piece on the last square of a ray cannot block anything beyond it
+		rookMask := rayAttacks(pos, 0, forwardDir|backDir)&^(bottomEdge|topEdge) |
+			rayAttacks(pos, 0, leftDir|rightDir)&^(leftEdge|rightEdge)
+		bishopMask := rayAttacks(pos, 0, diagDirs) &^ (bottomEdge | topEdge | leftEdge | rightEdge)
+		rookMagics[sq] = newMagic(pos, rookMask, rookMagicNumbers[sq], straightDirs)
+		bishopMagics[sq] = newMagic(pos, bishopMask, bishopMagicNumbers[sq], diagDirs)
+	}
+	rookMagics[64] = magic{attacks: []uint64{0}, shift: 63}
+	bishopMagics[64] = magic{attacks: []uint64{0}, shift: 63}
+}
+
+// newMagic fills in the attacks of a slider on pos for every occupancy of the mask.
+func newMagic(pos, mask, number uint64, dirs uint8) magic {
+	n := bits.OnesCount64(mask)
+	m := magic{mask: mask, magic: number, shift: uint(64 - n), attacks: make([]uint64, 1<<n)}
+	// walk through every subset of the mask
+	for occ := uint64(0); ; {
+		m.attacks[m.index(occ)] = rayAttacks(pos, occ, dirs)
+		if occ = (occ - mask) & mask; occ == 0 {
+			break
+		}
+	}
+	return m
+}
+
+// rookAttacks returns the squares a rook on pos attacks, given the occupancy.
+func rookAttacks(pos, occupied uint64) uint64 {
+	m := &rookMagics[bits.TrailingZeros64(pos)]
+	return m.attacks[m.index(occupied)]
+}
+
+// bishopAttacks returns the squares a bishop on pos attacks, given the occupancy.
+func bishopAttacks(pos, occupied uint64) uint64 {
+	m := &bishopMagics[bits.TrailingZeros64(pos)]
+	return m.attacks[m.index(occupied)]
+}
+
+// knightJumps returns the squares a knight on pos jumps to, worked out by shifting.
+// It is only used to fill in the table.
+func knightJumps(pos uint64) uint64 {
+	var attacks uint64
+	attacks |= (pos & ^rightEdge & ^rightButOneEdge) << 6
+	attacks |= (pos & ^rightEdge) << 15
+	attacks |= (pos & ^leftEdge) << 17
+	attacks |= (pos & ^leftEdge & ^leftButOneEdge) << 10
+	attacks |= (pos & ^leftEdge & ^leftButOneEdge) >> 6
+	attacks |= (pos & ^leftEdge) >> 15
+	attacks |= (pos & ^rightEdge) >> 17
+	attacks |= (pos & ^rightEdge & ^rightButOneEdge) >> 10
+	return attacks
+}
+
+// rayAttacks walks the rays from pos in the given directions one square at a time,
+// stopping at the first occupied square of each ray. It is only used to fill in the
+// tables.
+func rayAttacks(pos, occupied uint64, dirs uint8) uint64 {
+	var attacks uint64
+	type ray struct {
+		dir   uint8
+		edge  uint64
+		shift int
+	}
+	// edge is the file a square must not be on to step in that direction,
+	// a positive shift steps up the board and a negative one steps down
+	rays := []ray{
+		{diagBackRightDir, rightEdge, -9},
+		{backDir, 0, -8},
+		{diagBackLeftDir, leftEdge, -7},
+		{rightDir, rightEdge, -1},
+		{leftDir, leftEdge, 1},
+		{diagForwardRightDir, rightEdge, 7},
+		{forwardDir, 0, 8},
+		{diagForwardLeftDir, leftEdge, 9},
+	}
+	for _, r := range rays {
+		if dirs&r.dir == 0 {
+			continue
+		}
+		cur := pos
+		for {
+			if cur&r.edge != 0 {
+				break
+			}
+			if r.shift > 0 {
+				cur <<= uint(r.shift)
+			} else {
+				cur >>= uint(-r.shift)
+			}
+			if cur == 0 {
+				break
+			}
+			attacks |= cur
+			if cur&occupied != 0 {
+				break
+			}
+		}
+	}
+	return attacks
+}
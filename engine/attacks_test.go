@@ -0,0 +1,75 @@
+package engine
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// walkAttacks works out attacks from square sq the slow way, stepping by file and rank
+// offsets, with slide continuing along each offset until a square is occupied.
+func walkAttacks(sq int, occupied uint64, steps [][2]int, slide bool) uint64 {
+	var attacks uint64
+	for _, step := range steps {
+		col, row := sq%8+step[0], sq/8+step[1]
+		for col >= 0 && col < 8 && row >= 0 && row < 8 {
+			pos := uint64(1) << (row*8 + col)
+			attacks |= pos
+			if !slide || occupied&pos != 0 {
+				break
+			}
+			col, row = col+step[0], row+step[1]
+		}
+	}
+	return attacks
+}
+
+var (
+	rookSteps   = [][2]int{{1, 0}, {-1, 0}, {0, 1}, {0, -1}}
+	bishopSteps = [][2]int{{1, 1}, {1, -1}, {-1, 1}, {-1, -1}}
+	kingSteps   = append(append([][2]int{}, rookSteps...), bishopSteps...)
+	knightSteps = [][2]int{{1, 2}, {2, 1}, {2, -1}, {1, -2}, {-1, -2}, {-2, -1}, {-2, 1}, {-1, 2}}
+)
+
+func TestSliderAttacks(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for sq := 0; sq < 64; sq++ {
+		pos := uint64(1) << sq
+		for i := 0; i < 1000; i++ {
+			// sparse and dense boards alike
+			occupied := r.Uint64() & r.Uint64()
+			if i%2 == 0 {
+				occupied |= r.Uint64()
+			}
+			if got, want := rookAttacks(pos, occupied), walkAttacks(sq, occupied, rookSteps, true); got != want {
+				t.Fatalf("rook on %s with occupancy %016x: attacks %016x, want %016x", uciSquare(pos), occupied, got, want)
+			}
+			if got, want := bishopAttacks(pos, occupied), walkAttacks(sq, occupied, bishopSteps, true); got != want {
+				t.Fatalf("bishop on %s with occupancy %016x: attacks %016x, want %016x", uciSquare(pos), occupied, got, want)
+			}
+		}
+	}
+}
+
+func TestLeaperAttacks(t *testing.T) {
+	for sq := 0; sq < 64; sq++ {
+		pos := uint64(1) << sq
+		if got, want := knightAttacks(pos), walkAttacks(sq, 0, knightSteps, false); got != want {
+			t.Errorf("knight on %s: attacks %016x, want %016x", uciSquare(pos), got, want)
+		}
+		if got, want := kingAttacks(pos), walkAttacks(sq, 0, kingSteps, false); got != want {
+			t.Errorf("king on %s: attacks %016x, want %016x", uciSquare(pos), got, want)
+		}
+		if got, want := pawnAttacks(pos, true), walkAttacks(sq, 0, [][2]int{{1, 1}, {-1, 1}}, false); got != want {
+			t.Errorf("white pawn on %s: attacks %016x, want %016x", uciSquare(pos), got, want)
+		}
+		if got, want := pawnAttacks(pos, false), walkAttacks(sq, 0, [][2]int{{1, -1}, {-1, -1}}, false); got != want {
+			t.Errorf("black pawn on %s: attacks %016x, want %016x", uciSquare(pos), got, want)
+		}
+	}
+}
+
+func TestAttacksOfNoSquare(t *testing.T) {
+	if rookAttacks(0, ^uint64(0)) != 0 || bishopAttacks(0, 0) != 0 || knightAttacks(0) != 0 || kingAttacks(0) != 0 {
+		t.Error("an empty bitboard attacks squares")
+	}
+}
@@ -2,6 +2,7 @@ package engine
 
 import (
 	"fmt"
+	"math/bits"
 	"strings"
 )
 
@@ -19,23 +20,16 @@ const (
 
 // Iinitializes the chess board with the starting positions of the pieces.
 func (b *Board) Initialize() {
-	b.whitePawns = 0x000000000000FF00
-	b.whiteKnights = 0x0000000000000042
-	b.whiteBishops = 0x0000000000000024
-	b.whiteRooks = 0x0000000000000081
-	b.whiteQueens = 0x0000000000000010
-	b.whiteKing = 0x0000000000000008
-	b.whitePieces = 0x000000000000FFFF
-
-	b.blackPawns = 0x00FF000000000000
-	b.blackKnights = 0x4200000000000000
-	b.blackBishops = 0x2400000000000000
-	b.blackRooks = 0x8100000000000000
-	b.blackQueens = 0x1000000000000000
-	b.blackKing = 0x0800000000000000
-	b.blackPieces = 0xFFFF000000000000
-
-	b.allPieces = 0xFFFF00000000FFFF
+	// white's pieces by kind; black's are the same mirrored onto the other side
+	start := [6]uint64{0x000000000000FF00, 0x42, 0x24, 0x81, 0x10, 0x08}
+	*b = Board{}
+	for idx, pieces := range start {
+		for ; pieces != 0; pieces &= pieces - 1 {
+			pos := pieces & -pieces
+			b.movePiece(0, pos, pieceTypes[idx], true)
+			b.movePiece(0, bits.ReverseBytes64(pos), pieceTypes[idx], false)
+		}
+	}
 }
 
 // Print prints the chess board to the console.
@@ -56,9 +50,9 @@ func (b *Board) PrintBoard(isWhite bool, bestMov uint64) {
 				print(colorYellow)
 			}
 			pieceTypeString := string(rune(b.getPieceType(i)))
-			if b.whitePieces&i != 0 {
+			if b.colours[whiteIndex]&i != 0 {
 				fmt.Print(pieceTypeString)
-			} else if b.blackPieces&i != 0 {
+			} else if b.colours[blackIndex]&i != 0 {
 				fmt.Print(strings.ToLower(pieceTypeString))
 			} else {
 				fmt.Printf(" ")
@@ -91,9 +85,9 @@ func (b *Board) PrintBoard(isWhite bool, bestMov uint64) {
 				print(colorYellow)
 			}
 			pieceTypeString := string(rune(b.getPieceType(i)))
-			if b.whitePieces&i != 0 {
+			if b.colours[whiteIndex]&i != 0 {
 				fmt.Print(pieceTypeString)
-			} else if b.blackPieces&i != 0 {
+			} else if b.colours[blackIndex]&i != 0 {
 				fmt.Print(strings.ToLower(pieceTypeString))
 			} else {
 				fmt.Printf(" ")
@@ -135,64 +129,65 @@ func printBitBoard(bitBoard uint64) {
 
 // getColour returns the colour of the piece at a given position.
 func (b *Board) getColour(pos uint64) bool {
-	return pos&b.whitePieces != 0
+	return pos&b.colours[whiteIndex] != 0
 }
 
 // getPieceType returns the type of the piece at a given position.
 func (b *Board) getPieceType(pos uint64) PieceType {
-	if pos&b.whitePawns != 0 || pos&b.blackPawns != 0 {
-		return Pawn
-	} else if pos&b.whiteKnights != 0 || pos&b.blackKnights != 0 {
-		return Knight
-	} else if pos&b.whiteBishops != 0 || pos&b.blackBishops != 0 {
-		return Bishop
-	} else if pos&b.whiteRooks != 0 || pos&b.blackRooks != 0 {
-		return Rook
-	} else if pos&b.whiteQueens != 0 || pos&b.blackQueens != 0 {
-		return Queen
-	} else if pos&b.whiteKing != 0 || pos&b.blackKing != 0 {
-		return King
+	if pos&b.allPieces == 0 {
+		return NoPiece
 	}
-	return NoPiece
+	return b.mailbox[bits.TrailingZeros64(pos)]
 }
 
 // check if a king is currently under attack
 func (b *Board) isCheck(isWhite bool) bool {
-	oppMoves := b.getAllMoves(!isWhite)
-	var kingPos uint64
-	if isWhite {
-		kingPos = b.whiteKing
-	} else {
-		kingPos = b.blackKing
-	}
-	for _, move := range oppMoves {
-		if move[1]&kingPos != 0 {
-			return true
-		}
-	}
-	return false
+	return b.isAttacked(b.pieceBitboard(King, isWhite), !isWhite)
 }
 
 // check if the game has ended or not
 func (b *Board) isCheckmate(isWhite bool) bool {
 	if b.isCheck(isWhite) {
-		if len(b.getAllLegalMoves(isWhite)) == 0 {
+		if !b.hasLegalMove(isWhite) {
 			return true
 		}
 	}
 	return false
 }
 
+// check if the side to move has no legal moves while not in check
+func (b *Board) isStalemate(isWhite bool) bool {
+	return !b.isCheck(isWhite) && !b.hasLegalMove(isWhite)
+}
+
+// hasInsufficientMaterial reports whether neither side can possibly deliver mate:
+// bare kings, a single minor piece, or only bishops that all stand on one square colour.
+func (b *Board) hasInsufficientMaterial() bool {
+	if b.kindBitboard(pawnIndex)|b.kindBitboard(rookIndex)|b.kindBitboard(queenIndex) != 0 {
+		return false
+	}
+	knights := b.kindBitboard(knightIndex)
+	bishops := b.kindBitboard(bishopIndex)
+	if bits.OnesCount64(knights|bishops) <= 1 {
+		return true
+	}
+	return knights == 0 && (bishops&darkSquares == 0 || bishops&^darkSquares == 0)
+}
+
+// hasMatingMaterial reports whether the given side has enough material left to
+// mate a bare king: any pawn, rook or queen, or at least two minor pieces.
+func (b *Board) hasMatingMaterial(isWhite bool) bool {
+	own := &b.pieces[colourIndex(isWhite)]
+	return own[pawnIndex]|own[rookIndex]|own[queenIndex] != 0 || bits.OnesCount64(own[knightIndex]|own[bishopIndex]) >= 2
+}
+
 func test() {
 	var b Board = parse("7k/3p3P/4npPK/2N5/8/8/8/8 w - - 0 1")
 	b.makeMove(0x2000000000, 0x080000000000, true, Knight)
-	moves := b.getAllLegalMoves(false)
-	for _, move := range moves {
-		for i := 0; i < 64; i++ {
-			if move[1]&(1<<uint(i)) != 0 {
-				printBitBoard(1 << uint(i))
-			}
-		}
+	var moves MoveList
+	b.generateMoves(false, &moves)
+	for _, move := range moves.slice() {
+		printBitBoard(move.to())
 	}
 	b.PrintBoard(true, 0)
 }
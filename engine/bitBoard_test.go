@@ -0,0 +1,101 @@
+package engine
+
+import (
+	"math/bits"
+	"math/rand"
+	"testing"
+)
+
+// checkBoard reports where the bitboards, the mailbox and the hashes of a board
+// disagree.
+func checkBoard(t *testing.T, context string, b *Board) {
+	t.Helper()
+	var seen uint64
+	for colour := range b.pieces {
+		var own uint64
+		for idx, pieces := range b.pieces[colour] {
+			if pieces&seen != 0 {
+				t.Fatalf("%s: pieces share a square", context)
+			}
+			seen |= pieces
+			own |= pieces
+			for ; pieces != 0; pieces &= pieces - 1 {
+				if sq := bits.TrailingZeros64(pieces); b.mailbox[sq] != pieceTypes[idx] {
+					t.Fatalf("%s: mailbox has %c on %s, bitboards %c", context, b.mailbox[sq], uciSquare(pieces&-pieces), pieceTypes[idx])
+				}
+			}
+		}
+		if b.colours[colour] != own {
+			t.Fatalf("%s: colour bitboard %016x, pieces %016x", context, b.colours[colour], own)
+		}
+	}
+	if b.allPieces != seen {
+		t.Fatalf("%s: occupancy %016x, pieces %016x", context, b.allPieces, seen)
+	}
+	for sq := 0; sq < 64; sq++ {
+		if pos := uint64(1) << sq; pos&seen == 0 && b.getPieceType(pos) != NoPiece {
+			t.Fatalf("%s: %c on the empty square %s", context, b.getPieceType(pos), uciSquare(pos))
+		}
+	}
+	if hash, pawnHash := b.computeHash(); b.hash != hash || b.pawnHash != pawnHash {
+		t.Fatalf("%s: hashes %016x %016x, from scratch %016x %016x", context, b.hash, b.pawnHash, hash, pawnHash)
+	}
+}
+
+// randomPlayouts plays random legal moves from each position and takes them back
+// again, calling check on the board after every move and every move taken back.
+func randomPlayouts(t *testing.T, r *rand.Rand, fens []string, plies int, check func(context string, b *Board, whiteToMove bool)) {
+	t.Helper()
+	for _, fen := range fens {
+		g := parseGame(fen)
+		b, isWhite := &g.board, g.whiteToMove
+		start := *b
+		type played struct {
+			move Move
+			undo moveUndo
+		}
+		var line []played
+		for ply := 0; ply < plies; ply++ {
+			var moves MoveList
+			b.generateMoves(isWhite, &moves)
+			if moves.len() == 0 {
+				break
+			}
+			m := moves.slice()[r.Intn(moves.len())]
+			line = append(line, played{m, b.doMove(m, isWhite)})
+			isWhite = !isWhite
+			check(fen+" after "+m.String(), b, isWhite)
+		}
+		for i := len(line) - 1; i >= 0; i-- {
+			isWhite = !isWhite
+			b.undoMove(line[i].move, isWhite, line[i].undo)
+			check(fen+" taking back "+line[i].move.String(), b, isWhite)
+		}
+		// check may have filled in the sums or the accumulator, which start lacks
+		end := *b
+		end.sums, end.acc = start.sums, start.acc
+		if end != start {
+			t.Fatalf("%s: board not the same after taking every move back", fen)
+		}
+	}
+}
+
+func boardTestFens() []string {
+	fens := append([]string(nil), evalTestFens...)
+	for _, p := range perftPositions {
+		fens = append(fens, p.Fen)
+	}
+	return fens
+}
+
+func TestBoardStaysConsistent(t *testing.T) {
+	for _, fen := range boardTestFens() {
+		checkBoard(t, fen, testBoard(fen))
+	}
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 20; i++ {
+		randomPlayouts(t, r, boardTestFens(), 100, func(context string, b *Board, whiteToMove bool) {
+			checkBoard(t, context, b)
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package engine
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/bits"
+	"math/rand"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Book is a Polyglot opening book: entries of 16 bytes, sorted by position key.
+type Book struct {
+	entries []bookEntry
+}
+
+type bookEntry struct {
+	Key    uint64
+	Move   uint16
+	Weight uint16
+	Learn  uint32
+}
+
+// BookMove is a move found in the book for a position.
+type BookMove struct {
+	Move   string // in uci notation, e.g. e2e4
+	Weight int
+}
+
+// openingBook is the book the engine plays from when ownBook is set.
+var (
+	openingBook *Book
+	ownBook     bool
+)
+
+// LoadBook reads a Polyglot book file.
+func LoadBook(path string) (*Book, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size()%16 != 0 {
+		return nil, fmt.Errorf("reading book %s: size is not a multiple of 16 bytes", path)
+	}
+	book := &Book{entries: make([]bookEntry, info.Size()/16)}
+	if err := binary.Read(file, binary.BigEndian, book.entries); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("reading book %s: %w", path, err)
+	}
+	return book, nil
+}
+
+// lookup returns the entries for a position key.
+func (bk *Book) lookup(key uint64) []bookEntry {
+	first := sort.Search(len(bk.entries), func(i int) bool { return bk.entries[i].Key >= key })
+	last := first
+	for last < len(bk.entries) && bk.entries[last].Key == key {
+		last++
+	}
+	return bk.entries[first:last]
+}
+
+// bookMoveSquares decodes a Polyglot move into its from and to squares and the
+// promotion piece, if any. Castling is stored as the king taking its own rook.
+func bookMoveSquares(move uint16) (initPos, finalPos uint64, promotion PieceType) {
+	square := func(file, row uint16) uint64 {
+		return 1 << (row*8 + 7 - file)
+	}
+	finalPos = square(move&7, move>>3&7)
+	initPos = square(move>>6&7, move>>9&7)
+	promotion = NoPiece
+	if p := move >> 12 & 7; p != 0 {
+		promotion = []PieceType{Knight, Bishop, Rook, Queen}[p-1]
+	}
+	return initPos, finalPos, promotion
+}
+
+// uciSquare names a square, e.g. e4.
+func uciSquare(pos uint64) string {
+	sq := bits.TrailingZeros64(pos)
+	return string(rune('a'+squareFile(sq))) + string(rune('1'+squareRank(sq)))
+}
+
+func bookMoveString(move uint16) string {
+	initPos, finalPos, promotion := bookMoveSquares(move)
+	s := uciSquare(initPos) + uciSquare(finalPos)
+	if promotion != NoPiece {
+		s += strings.ToLower(string(rune(promotion)))
+	}
+	return s
+}
+
+// bookMove picks a move for the game from the book, at random in proportion to the
+// move weights. Moves that are not legal in the position are skipped. ok is false when
+// the book has no playable move.
+func (g *Game) bookMove(bk *Book) (Move, bool) {
+	type candidate struct {
+		move   Move
+		weight int
+	}
+	var candidates []candidate
+	total := 0
+	for _, entry := range bk.lookup(g.polyglotKey()) {
+		from, to, promotion := bookMoveSquares(entry.Move)
+		// castling is stored as the king taking its own rook
+		if from&g.board.pieceBitboard(King, g.whiteToMove) != 0 && to&g.board.pieceBitboard(Rook, g.whiteToMove) != 0 {
+			to, _, _ = castlingRookSquares(from, to > from)
+		}
+		move, ok := g.findMove(from, to, promotion)
+		if entry.Weight == 0 || !ok {
+			continue
+		}
+		candidates = append(candidates, candidate{move, int(entry.Weight)})
+		total += int(entry.Weight)
+	}
+	if total == 0 {
+		return noMove, false
+	}
+	pick := rand.Intn(total)
+	for _, c := range candidates {
+		if pick < c.weight {
+			return c.move, true
+		}
+		pick -= c.weight
+	}
+	return noMove, false
+}
+
+// ProbeBook looks a position given as a fen string up in a book and returns its key
+// and the book moves, best weighted first.
+func ProbeBook(bookPath, fen string) (uint64, []BookMove, error) {
+	bk, err := LoadBook(bookPath)
+	if err != nil {
+		return 0, nil, err
+	}
+	g := parseGame(fen)
+	if g.board.pieces[whiteIndex][kingIndex] == 0 || g.board.pieces[blackIndex][kingIndex] == 0 {
+		return 0, nil, fmt.Errorf("invalid fen: both sides need a king")
+	}
+	key := g.polyglotKey()
+	var moves []BookMove
+	for _, entry := range bk.lookup(key) {
+		moves = append(moves, BookMove{bookMoveString(entry.Move), int(entry.Weight)})
+	}
+	sort.SliceStable(moves, func(i, j int) bool { return moves[i].Weight > moves[j].Weight })
+	return key, moves, nil
+}
@@ -0,0 +1,171 @@
+package engine
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// BookBuildStats summarises a book built from a PGN file.
+type BookBuildStats struct {
+	Games     int // games read
+	Skipped   int // games without a result
+	Errors    int // games cut short by a move that could not be read
+	Positions int // positions in the book
+	Entries   int // moves in the book
+}
+
+// bookMoveStats counts how a move fared in a position.
+type bookMoveStats struct {
+	games int
+	score int // 2 for each win and 1 for each draw, for the side that played it
+}
+
+// BuildBook makes a Polyglot book from the games in a PGN file. Every move played in
+// the first maxPly plies of a game is counted, and moves played in at least minGames
+// games go into the book, weighted by how they scored: 2 points per win and 1 per draw,
+// so a move's weight grows both with how often it was played and how well it did.
+func BuildBook(pgnPath, outPath string, maxPly, minGames int) (BookBuildStats, error) {
+	var stats BookBuildStats
+	file, err := os.Open(pgnPath)
+	if err != nil {
+		return stats, err
+	}
+	defer file.Close()
+	games, err := readPGN(file)
+	if err != nil {
+		return stats, fmt.Errorf("reading %s: %w", pgnPath, err)
+	}
+
+	positions := map[uint64]map[uint16]*bookMoveStats{}
+	for n, pgn := range games {
+		stats.Games++
+		var whiteScore int
+		switch pgn.result {
+		case "1-0":
+			whiteScore = 2
+		case "1/2-1/2":
+			whiteScore = 1
+		case "0-1":
+			whiteScore = 0
+		default:
+			stats.Skipped++
+			continue
+		}
+
+		g := gameFromTags(pgn.tags)
+		for ply, pm := range pgn.moves {
+			if ply >= maxPly {
+				break
+			}
+			m, err := g.parseSAN(pm.san)
+			if err != nil {
+				fmt.Printf("game %d, ply %d: %v\n", n+1, ply+1, err)
+				stats.Errors++
+				break
+			}
+			key := g.polyglotKey()
+			move := polyglotMove(m)
+			if positions[key] == nil {
+				positions[key] = map[uint16]*bookMoveStats{}
+			}
+			s := positions[key][move]
+			if s == nil {
+				s = &bookMoveStats{}
+				positions[key][move] = s
+			}
+			s.games++
+			if g.whiteToMove {
+				s.score += whiteScore
+			} else {
+				s.score += 2 - whiteScore
+			}
+			g.play(m)
+		}
+	}
+
+	book := &Book{}
+	maxScore := 0
+	for _, moves := range positions {
+		for _, s := range moves {
+			if s.games >= minGames {
+				maxScore = max(maxScore, s.score)
+			}
+		}
+	}
+	for key, moves := range positions {
+		inBook := false
+		for move, s := range moves {
+			if s.games < minGames || s.score == 0 {
+				continue
+			}
+			weight := s.score
+			if maxScore > 0xFFFF {
+				weight = max(weight*0xFFFF/maxScore, 1)
+			}
+			book.entries = append(book.entries, bookEntry{Key: key, Move: move, Weight: uint16(weight)})
+			inBook = true
+		}
+		if inBook {
+			stats.Positions++
+		}
+	}
+	stats.Entries = len(book.entries)
+	return stats, book.Save(outPath)
+}
+
+// gameFromTags starts a game from the FEN tag of a PGN game, or from the start position.
+func gameFromTags(tags map[string]string) Game {
+	if fen, ok := tags["FEN"]; ok {
+		return parseGame(fen)
+	}
+	var b Board
+	b.Initialize()
+	return newGame(b, true)
+}
+
+// polyglotMove encodes a move for a Polyglot book. Castling is written as the king
+// taking its own rook.
+func polyglotMove(m Move) uint16 {
+	initPos, finalPos := m.from(), m.to()
+	if m.isCastle() {
+		_, rookFrom, _ := castlingRookSquares(initPos, finalPos > initPos)
+		finalPos = rookFrom
+	}
+	move := uint16(polyglotSquare(finalPos)) | uint16(polyglotSquare(initPos))<<6
+	switch m.promotion() {
+	case Knight:
+		move |= 1 << 12
+	case Bishop:
+		move |= 2 << 12
+	case Rook:
+		move |= 3 << 12
+	case Queen:
+		move |= 4 << 12
+	}
+	return move
+}
+
+// Save writes the book to a Polyglot file, sorted by key with the heaviest moves first.
+func (bk *Book) Save(path string) error {
+	sort.Slice(bk.entries, func(i, j int) bool {
+		a, b := bk.entries[i], bk.entries[j]
+		if a.Key != b.Key {
+			return a.Key < b.Key
+		}
+		if a.Weight != b.Weight {
+			return a.Weight > b.Weight
+		}
+		return a.Move < b.Move
+	})
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := binary.Write(file, binary.BigEndian, bk.entries); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,95 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const bookTestPGN = `[Event "one"]
+[Result "1-0"]
+
+1. e4 e5 2. Nf3 1-0
+
+[Event "two"]
+[Result "1/2-1/2"]
+
+1. e4 e5 2. Nf3 {a comment} 1/2-1/2
+
+[Event "three"]
+[Result "0-1"]
+
+1. e4 c5 0-1
+
+[Event "unfinished"]
+[Result "*"]
+
+1. d4 *
+
+[Event "illegal"]
+[Result "1-0"]
+
+1. e4 Ke7 1-0
+`
+
+func TestBuildBook(t *testing.T) {
+	dir := t.TempDir()
+	pgnPath, bookPath := filepath.Join(dir, "games.pgn"), filepath.Join(dir, "book.bin")
+	if err := os.WriteFile(pgnPath, []byte(bookTestPGN), 0644); err != nil {
+		t.Fatal(err)
+	}
+	e4 := newTestGame(t, "startpos moves e2e4")
+	afterE4 := e4.fen()
+
+	tests := []struct {
+		minGames  int
+		stats     BookBuildStats
+		start, e4 []BookMove
+	}{
+		// e4 scores 2+1+0+2 out of four games, the game with the illegal reply counting
+		// up to that reply; black scores 1 with e5 and 2 with c5
+		{1, BookBuildStats{Games: 5, Skipped: 1, Errors: 1, Positions: 2, Entries: 3},
+			[]BookMove{{"e2e4", 5}}, []BookMove{{"c7c5", 2}, {"e7e5", 1}}},
+		{2, BookBuildStats{Games: 5, Skipped: 1, Errors: 1, Positions: 2, Entries: 2},
+			[]BookMove{{"e2e4", 5}}, []BookMove{{"e7e5", 1}}},
+	}
+	for _, tt := range tests {
+		stats, err := BuildBook(pgnPath, bookPath, 2, tt.minGames)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stats != tt.stats {
+			t.Errorf("min games %d: stats %+v, want %+v", tt.minGames, stats, tt.stats)
+		}
+		for _, probe := range []struct {
+			fen  string
+			want []BookMove
+		}{{startPosition, tt.start}, {afterE4, tt.e4}} {
+			_, moves, err := ProbeBook(bookPath, probe.fen)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(moves, probe.want) {
+				t.Errorf("min games %d: book moves %v in %s, want %v", tt.minGames, moves, probe.fen, probe.want)
+			}
+		}
+	}
+}
+
+func TestPolyglotMove(t *testing.T) {
+	for _, fen := range []string{startPosition, perftPositions[1].Fen, perftPositions[3].Fen} {
+		g := parseGame(fen)
+		var moves MoveList
+		g.board.generateMoves(g.whiteToMove, &moves)
+		for _, m := range moves.slice() {
+			from, to, promotion := bookMoveSquares(polyglotMove(m))
+			if m.isCastle() {
+				to, _, _ = castlingRookSquares(from, to > from)
+			}
+			if from != m.from() || to != m.to() || promotion != m.promotion() {
+				t.Errorf("%s: %v comes back from the book as %s", fen, m, bookMoveString(polyglotMove(m)))
+			}
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package engine
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestPolyglotKey checks the keys given in the Polyglot book format description.
+func TestPolyglotKey(t *testing.T) {
+	tests := []struct {
+		moves string
+		key   uint64
+	}{
+		{"", 0x463b96181691fc9c},
+		{"e2e4", 0x823c9b50fd114196},
+		{"e2e4 d7d5", 0x0756b94461c50fb0},
+		{"e2e4 d7d5 e4e5", 0x662fafb965db29d4},
+		{"e2e4 d7d5 e4e5 f7f5", 0x22a48b5a8e47ff78},
+		{"e2e4 d7d5 e4e5 f7f5 e1e2", 0x652a607ca3f242c1},
+		{"e2e4 d7d5 e4e5 f7f5 e1e2 e8f7", 0x00fdd303c946bdd9},
+		{"a2a4 b7b5 h2h4 b5b4 c2c4", 0x3c8123ea7b067637},
+		{"a2a4 b7b5 h2h4 b5b4 c2c4 b4c3 a1a3", 0x5c3f9b829b279560},
+	}
+	for _, tt := range tests {
+		g := newTestGame(t, "startpos moves "+tt.moves)
+		if got := g.polyglotKey(); got != tt.key {
+			t.Errorf("after %q: key %016x, want %016x", tt.moves, got, tt.key)
+		}
+	}
+}
+
+// polyglotTestMove encodes a move given in uci notation the way a Polyglot book
+// stores it.
+func polyglotTestMove(uci string) uint16 {
+	square := func(s string) uint16 {
+		return uint16(s[0]-'a') + uint16(s[1]-'1')*8
+	}
+	return square(uci[2:4]) | square(uci[0:2])<<6
+}
+
+func writeTestBook(t *testing.T, entries []bookEntry) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "book.bin")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if err := binary.Write(file, binary.BigEndian, entries); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestProbeBook(t *testing.T) {
+	start := uint64(0x463b96181691fc9c)
+	path := writeTestBook(t, []bookEntry{
+		{Key: start - 1, Move: polyglotTestMove("a2a3"), Weight: 100},
+		{Key: start, Move: polyglotTestMove("d2d4"), Weight: 5},
+		{Key: start, Move: polyglotTestMove("e2e4"), Weight: 10},
+		{Key: start + 1, Move: polyglotTestMove("h2h3"), Weight: 100},
+	})
+	key, moves, err := ProbeBook(path, startPosition)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []BookMove{{"e2e4", 10}, {"d2d4", 5}}
+	if key != start || len(moves) != len(want) || moves[0] != want[0] || moves[1] != want[1] {
+		t.Errorf("probe gave key %016x and moves %v, want %v", key, moves, want)
+	}
+
+	bad := filepath.Join(t.TempDir(), "bad.bin")
+	if err := os.WriteFile(bad, make([]byte, 20), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadBook(bad); err == nil {
+		t.Error("book that is not a multiple of 16 bytes was accepted")
+	}
+}
+
+func TestBookMove(t *testing.T) {
+	g := parseGame("r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1")
+	key := g.polyglotKey()
+	path := writeTestBook(t, []bookEntry{
+		{Key: key, Move: polyglotTestMove("e1h1"), Weight: 10}, // castling, as the king taking its rook
+		{Key: key, Move: polyglotTestMove("a1a3"), Weight: 0},  // never played
+		{Key: key, Move: polyglotTestMove("e1e3"), Weight: 50}, // not legal
+	})
+	bk, err := LoadBook(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20; i++ {
+		m, ok := g.bookMove(bk)
+		if !ok || !m.isCastle() || m.to() != g.board.notationToPos("g1") {
+			t.Fatalf("book move %v, want castling short", m)
+		}
+	}
+
+	g = parseGame(startPosition)
+	if _, ok := g.bookMove(bk); ok {
+		t.Error("book move found for a position not in the book")
+	}
+}
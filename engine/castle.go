@@ -1,116 +1,56 @@
 package engine
 
-import (
-	"fmt"
-)
-
-var castleMoveInfo = map[string]*CastleMoveInfo{
-	"O-O":   {Positions: []string{"f1", "g1"}, CanCastle: true, RandomMove: [2]uint64{0xFFFFFFFFFFFFFFFF, 0xFFFFFFFFFFFFFFFF}, KingMove: "Ke1-g1", RookMove: "Rh1-f1"},   //white king
-	"O-O-O": {Positions: []string{"d1", "c1"}, CanCastle: true, RandomMove: [2]uint64{0xFFFFFFFFFFFFFF00, 0xFFFFFFFFFFFFFF00}, KingMove: "Ke1-c1", RookMove: "Re1-d1"}, //white queen
-	"o-o":   {Positions: []string{"f8", "g8"}, CanCastle: true, RandomMove: [2]uint64{0xFFFFFFFFFFFF000, 0xFFFFFFFFFFFF0000}, KingMove: "ke8-g8", RookMove: "rh8-f8"},   //black king
-	"o-o-o": {Positions: []string{"d8", "c8"}, CanCastle: true, RandomMove: [2]uint64{0xFFFFFFFFFF000000, 0xFFFFFFFFFF000000}, KingMove: "ke8-g8", RookMove: "ra8-d8"}, //black queen
-}
-
-func (b *Board) isCastleMove(move string) bool {
-	_, ok := castleMoveInfo[move]
-	fmt.Println(ok)
-	return ok
+// castlingSquares are the starting squares of the king and rook for each castling right.
+var castlingSquares = []struct {
+	right      uint8
+	isWhite    bool
+	king, rook uint64
+	fen        byte
+}{
+	{castleWhiteShort, true, 1 << 3, 1 << 0, 'K'},
+	{castleWhiteLong, true, 1 << 3, 1 << 7, 'Q'},
+	{castleBlackShort, false, 1 << 59, 1 << 56, 'k'},
+	{castleBlackLong, false, 1 << 59, 1 << 63, 'q'},
 }
 
-// func for getting all valid castle moves available for a situation for search to assess
-func (b *Board) getAllCastlingMoves(isWhite bool) [][2]uint64 {
-
-	var castleMoves [][2]uint64
-
-	if b.canCastleAll(isWhite) {
-		return nil
-	}
-
-	for key := range castleMoveInfo {
-		if b.validateCastleMove(isWhite, key) {
-			castleMoves = append(castleMoves, castleMoveInfo[key].RandomMove)
-		}
+// castlingRookSquares returns where the king goes when castling from the square king,
+// and where the rook comes from and goes to.
+func castlingRookSquares(king uint64, long bool) (kingTo, rookFrom, rookTo uint64) {
+	if long {
+		return king << 2, king << 4, king << 1
 	}
-
-	return castleMoves
+	return king >> 2, king >> 3, king >> 1
 }
 
-// just for validating if doing the castle move is legit
-// 1. the castling move is not done, and pieces are in their position (checking the struct for status)
-// 2. the squares between the king and rook are empty
-// 3. the squares the king moves through are not under attack
-// not checking for check as it is done prior overall, can use the canCastle func and not for a move in this helper func.
-
-func (b *Board) validateCastleMove(isWhite bool, castleMove string) bool {
-
-	for key, value := range castleMoveInfo {
-
-		if (key == castleMove) && value.CanCastle {
-			for _, pos := range value.Positions {
-				if b.validSquare(b.notationToPos(pos), isWhite) {
-					return true
-				}
-			}
+// generateCastling adds the castling moves of a side to the list. The side must still
+// have the right, the squares between king and rook must be empty, and the king may
+// not castle out of, through or into check.
+func (b *Board) generateCastling(isWhite bool, moves *MoveList) {
+	for _, c := range castlingSquares {
+		if c.isWhite != isWhite || b.castling&c.right == 0 {
+			continue
 		}
-
-	}
-
-	return false
-}
-
-// for checking if the king is in check and if already moved
-func (b *Board) canCastleAll(isWhite bool) bool {
-
-	i := 0
-	for _, value := range castleMoveInfo {
-		if i <= 1 {
-			if isWhite && !value.CanCastle {
-				return true
-			}
+		long := c.rook > c.king
+		kingTo, rookFrom, rookTo := castlingRookSquares(c.king, long)
+		if b.pieceBitboard(King, isWhite)&c.king == 0 || b.pieceBitboard(Rook, isWhite)&rookFrom == 0 {
+			continue
 		}
-		if i > 1 {
-			if !isWhite && !value.CanCastle {
-				return true
-			}
+		if b.allPieces&between(c.king, rookFrom) != 0 {
+			continue
 		}
+		if b.isAttacked(c.king, !isWhite) || b.isAttacked(rookTo, !isWhite) || b.isAttacked(kingTo, !isWhite) {
+			continue
+		}
+		moves.add(newMove(c.king, kingTo, NoPiece, moveCastle))
 	}
-
-	if b.isCheck(isWhite) {
-		return false
-	}
-	return false
-}
-
-func (b *Board) canCastle(isWhite bool, move string) bool {
-
-	if !castleMoveInfo[move].CanCastle {
-		fmt.Println("usbfdus")
-		return false
-	}
-	if b.isCheck(isWhite) {
-		fmt.Println("yahan")
-		return false
-	}
-	if !b.validateCastleMove(isWhite, move) {
-		fmt.Println("wahan")
-		return false
-	}
-
-	return true
-
 }
 
-func (b *Board) validSquare(pos uint64, isWhite bool) bool {
-	if b.getPieceType(pos) != NoPiece {
-		return false
-	}
-	legalMoves := b.getAllMoves(!isWhite)
-	for _, move := range legalMoves {
-		if move[1]&pos != 0 {
-			return false
+// updateCastling drops the castling rights a move gives up, by moving the king or a
+// rook or by taking a rook.
+func (b *Board) updateCastling(initPos, finalPos uint64) {
+	for _, c := range castlingSquares {
+		if initPos&(c.king|c.rook) != 0 || finalPos&c.rook != 0 {
+			b.castling &^= c.right
 		}
 	}
-	return true
 }
-
-// write such that the struct gets updated whenever there is a rook move
@@ -3,35 +3,58 @@ package engine
 // PieceType represents the type of a chess piece.
 type PieceType rune
 
-// Board represents the state of the chess board.
-type Board struct {
-	whitePawns   uint64
-	whiteKnights uint64
-	whiteBishops uint64
-	whiteRooks   uint64
-	whiteQueens  uint64
-	whiteKing    uint64
-	whitePieces  uint64
-
-	blackPawns   uint64
-	blackKnights uint64
-	blackBishops uint64
-	blackRooks   uint64
-	blackQueens  uint64
-	blackKing    uint64
-	blackPieces  uint64
+// Score is an evaluation in centipawns, from white's point of view unless stated otherwise.
+type Score int32
+
+const (
+	scoreDraw     Score = 0
+	scoreInfinite Score = 32000
+
+	// a side mated n plies from the root of the search scores -(scoreMate - n) and
+	// anything beyond scoreMateBound is a mate score rather than an evaluation
+	scoreMate      Score = 31000
+	maxPly               = 256
+	scoreMateBound Score = scoreMate - maxPly
+)
 
+// Board represents the state of the chess board. Every piece is kept both in the
+// bitboard of its colour and kind and in the mailbox, so the set of squares holding a
+// kind of piece and the piece standing on a square are both a single lookup.
+type Board struct {
+	pieces    [2][6]uint64 // by colour and kind, see colourIndex and pieceIndex
+	colours   [2]uint64    // every piece of each colour
 	allPieces uint64
-}
 
-type CastleMoveInfo struct {
-	Positions  []string
-	CanCastle  bool
-	RandomMove [2]uint64
-	KingMove string
-	RookMove string
+	mailbox [64]PieceType // the piece on each square, 0 when it is empty
+
+	castling uint8  // castleWhiteShort | castleWhiteLong | ...
+	epSquare uint64 // square passed by a pawn that just moved two ranks
+
+	hash     uint64 // zobrist hash of the piece placement, kept up to date by movePiece
+	pawnHash uint64 // zobrist hash of the pawns alone, for the pawn structure cache
+
+	acc  accumulator // network hidden layer, kept up to date by movePiece once computed
+	sums evalSums    // material, piece squares and phase, kept up to date by movePiece once computed
 }
 
+// Indexes into Board.pieces and Board.colours.
+const (
+	whiteIndex = 0
+	blackIndex = 1
+)
+
+const (
+	pawnIndex = iota
+	knightIndex
+	bishopIndex
+	rookIndex
+	queenIndex
+	kingIndex
+)
+
+// pieceTypes lists the piece types by index.
+var pieceTypes = [6]PieceType{Pawn, Knight, Bishop, Rook, Queen, King}
+
 const (
 	bottomEdge       uint64 = 0x00000000000000FF
 	bottomButOneEdge uint64 = 0x000000000000FF00
@@ -45,6 +68,8 @@ const (
 	topEdge       uint64 = 0xFF00000000000000
 	topButOneEdge uint64 = 0x00FF000000000000
 
+	darkSquares uint64 = 0x55AA55AA55AA55AA
+
 	diagBackRightDir    uint8 = 1 << 0
 	backDir             uint8 = 1 << 1
 	diagBackLeftDir     uint8 = 1 << 2
@@ -65,11 +90,4 @@ const (
 	Queen   PieceType = 'Q'
 	King    PieceType = 'K'
 	NoPiece PieceType = ' '
-
-	pawn_wt   = 100
-	knight_wt = 320
-	bishop_wt = 330
-	rook_wt   = 500
-	queen_wt  = 900
-	king_wt   = 20000
 )
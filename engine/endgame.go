@@ -0,0 +1,237 @@
+package engine
+
+import (
+	"math/bits"
+	"strings"
+)
+
+// scoreKnownWin is the score of an endgame the stronger side is known to win. The
+// endgame evaluations add to it how far the win has progressed, so the search makes
+// progress towards mate instead of shuffling.
+const scoreKnownWin Score = 10000
+
+// endgameEval scores a recognised endgame from the stronger side's point of view, or
+// returns false to leave the position to the general evaluation.
+type endgameEval func(b *Board, strongIsWhite, whiteToMove bool) (Score, bool)
+
+// endgames holds the endgames recognised by exact material, stronger side first.
+var endgames = map[string]endgameEval{
+	"KPvK":  evalKPK,
+	"KBNvK": evalKBNK,
+
+	// no side can force mate
+	"KvK":   evalDrawnEndgame,
+	"KNvK":  evalDrawnEndgame,
+	"KBvK":  evalDrawnEndgame,
+	"KNNvK": evalDrawnEndgame,
+	"KNvKN": evalDrawnEndgame,
+	"KBvKN": evalDrawnEndgame,
+	"KBvKB": evalDrawnEndgame,
+}
+
+// materialKey packs how many pawns, knights, bishops, rooks and queens a side has, four
+// bits each by piece index, so that endgames are looked up without naming them.
+type materialKey uint32
+
+// endgameKeys holds the endgames by the material keys of the stronger and weaker side.
+var endgameKeys = func() map[[2]materialKey]endgameEval {
+	keys := map[[2]materialKey]endgameEval{}
+	for name, f := range endgames {
+		strong, weak, _ := strings.Cut(name, "v")
+		keys[[2]materialKey{nameMaterialKey(strong), nameMaterialKey(weak)}] = f
+	}
+	return keys
+}()
+
+// nameMaterialKey returns the material key of a side named as in endgames, e.g. KBN.
+func nameMaterialKey(name string) materialKey {
+	var key materialKey
+	for _, r := range name {
+		if PieceType(r) != King {
+			key += 1 << (4 * pieceIndex(PieceType(r)))
+		}
+	}
+	return key
+}
+
+// materialKey returns the material key of a side's pieces.
+func (b *Board) materialKey(isWhite bool) materialKey {
+	var key materialKey
+	for idx, pieces := range b.pieces[colourIndex(isWhite)][:kingIndex] {
+		key |= materialKey(min(bits.OnesCount64(pieces), 15)) << (4 * idx)
+	}
+	return key
+}
+
+// materialString lists a side's pieces the way endgames and tablebase files are
+// named, e.g. KRP.
+func (b *Board) materialString(isWhite bool) string {
+	var sb strings.Builder
+	for _, pieceType := range []PieceType{King, Queen, Rook, Bishop, Knight, Pawn} {
+		sb.WriteString(strings.Repeat(string(rune(pieceType)), bits.OnesCount64(b.pieceBitboard(pieceType, isWhite))))
+	}
+	return sb.String()
+}
+
+// endgame recognises the endgame on the board. It returns the evaluation for it and
+// which side is the stronger one, or a nil evaluation for any other position.
+func (b *Board) endgame() (endgameEval, bool) {
+	// every endgame recognised leaves the weaker side a bare king or a lone minor piece
+	if bits.OnesCount64(b.colours[whiteIndex]) > 2 && bits.OnesCount64(b.colours[blackIndex]) > 2 {
+		return nil, false
+	}
+	white, black := b.materialKey(true), b.materialKey(false)
+	if f, ok := endgameKeys[[2]materialKey{white, black}]; ok {
+		return f, true
+	}
+	if f, ok := endgameKeys[[2]materialKey{black, white}]; ok {
+		return f, false
+	}
+	// a bare king against heavy pieces, or against a bishop and rook pawns
+	for _, strongIsWhite := range []bool{true, false} {
+		if b.colours[colourIndex(!strongIsWhite)] != b.pieceBitboard(King, !strongIsWhite) {
+			continue
+		}
+		pawns, bishops := b.pieceBitboard(Pawn, strongIsWhite), b.pieceBitboard(Bishop, strongIsWhite)
+		heavy := b.pieceBitboard(Queen, strongIsWhite) | b.pieceBitboard(Rook, strongIsWhite)
+		if pawns == 0 && heavy != 0 {
+			return evalKXK, strongIsWhite
+		}
+		others := b.colours[colourIndex(strongIsWhite)] &^ b.pieceBitboard(King, strongIsWhite) &^ pawns &^ bishops
+		if others == 0 && bishops != 0 && pawns != 0 {
+			return evalWrongBishop, strongIsWhite
+		}
+	}
+	return nil, false
+}
+
+// endgameName names the endgame on the board, stronger side first, e.g. KRvK, or
+// returns "" when it is not recognised.
+func (b *Board) endgameName() string {
+	f, strongIsWhite := b.endgame()
+	if f == nil {
+		return ""
+	}
+	return b.materialString(strongIsWhite) + "v" + b.materialString(!strongIsWhite)
+}
+
+// evalEndgame scores the board with the evaluation for its endgame, from white's point
+// of view. It returns false when the endgame is not recognised.
+func (b *Board) evalEndgame(whiteToMove bool) (Score, bool) {
+	f, strongIsWhite := b.endgame()
+	if f == nil {
+		return 0, false
+	}
+	score, ok := f(b, strongIsWhite, whiteToMove)
+	if !ok {
+		return 0, false
+	}
+	if !strongIsWhite {
+		score = -score
+	}
+	return score, true
+}
+
+func evalDrawnEndgame(b *Board, strongIsWhite, whiteToMove bool) (Score, bool) {
+	return scoreDraw, true
+}
+
+// squareDistance is the number of king moves between two squares.
+func squareDistance(a, b int) int {
+	return max(abs(a%8-b%8), abs(a/8-b/8))
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// pushToEdge rewards driving a king away from the centre, where it can be mated.
+func pushToEdge(sq int) Score {
+	file, rank := sq%8, sq/8
+	return Score(20 * (max(3-file, file-4) + max(3-rank, rank-4)))
+}
+
+// pushClose rewards bringing the kings together, as the stronger king has to help.
+func pushClose(a, b int) Score {
+	return Score(140 - 20*squareDistance(a, b))
+}
+
+// kings returns the king squares of the stronger and the weaker side.
+func (b *Board) kings(strongIsWhite bool) (int, int) {
+	strong := bits.TrailingZeros64(b.pieceBitboard(King, strongIsWhite))
+	weak := bits.TrailingZeros64(b.pieceBitboard(King, !strongIsWhite))
+	return strong, weak
+}
+
+// evalKXK mates a bare king with a queen or rook: the weak king is driven to the edge
+// and the strong king follows it.
+func evalKXK(b *Board, strongIsWhite, whiteToMove bool) (Score, bool) {
+	strongKing, weakKing := b.kings(strongIsWhite)
+	material, weakMaterial := b.evalMaterialValues()
+	if !strongIsWhite {
+		material = weakMaterial
+	}
+	return scoreKnownWin + Score(material.mg) + pushToEdge(weakKing) + pushClose(strongKing, weakKing), true
+}
+
+// evalKBNK mates with bishop and knight, which is only possible in a corner of the
+// bishop's colour, so the weak king is driven there.
+func evalKBNK(b *Board, strongIsWhite, whiteToMove bool) (Score, bool) {
+	strongKing, weakKing := b.kings(strongIsWhite)
+	corners := [2]int{0, 63} // h1 and a8, the light corners
+	if b.pieceBitboard(Bishop, strongIsWhite)&darkSquares != 0 {
+		corners = [2]int{7, 56} // a1 and h8
+	}
+	cornerDistance := min(squareDistance(weakKing, corners[0]), squareDistance(weakKing, corners[1]))
+	return scoreKnownWin + Score(40*(7-cornerDistance)) + pushToEdge(weakKing)/2 + pushClose(strongKing, weakKing), true
+}
+
+// evalKPK looks king and pawn against king up in the bitbase.
+func evalKPK(b *Board, strongIsWhite, whiteToMove bool) (Score, bool) {
+	strongKing, weakKing := b.kings(strongIsWhite)
+	pawn := bits.TrailingZeros64(b.pieceBitboard(Pawn, strongIsWhite))
+
+	// the bitbase numbers squares from a1 and has white as the stronger side with its
+	// pawn on the a to d files
+	strongKing, weakKing, pawn = strongKing^7, weakKing^7, pawn^7
+	if !strongIsWhite {
+		strongKing, weakKing, pawn = strongKing^56, weakKing^56, pawn^56
+	}
+	if pawn%8 > 3 {
+		strongKing, weakKing, pawn = strongKing^7, weakKing^7, pawn^7
+	}
+	if !kpkProbe(whiteToMove == strongIsWhite, strongKing, pawn, weakKing) {
+		return scoreDraw, true
+	}
+	return scoreKnownWin + Score(evalParams.PieceValues.Pawn) + Score(10*(pawn/8)), true
+}
+
+// evalWrongBishop recognises bishop and rook pawns against a bare king that holds the
+// promotion corner when the bishop does not control it: the pawns cannot be promoted.
+func evalWrongBishop(b *Board, strongIsWhite, whiteToMove bool) (Score, bool) {
+	pawns := b.pieceBitboard(Pawn, strongIsWhite)
+	var corner uint64
+	switch {
+	case pawns&^fileMasks[0] == 0:
+		corner = 1 << 63 // a8
+	case pawns&^fileMasks[7] == 0:
+		corner = 1 << 56 // h8
+	default:
+		return 0, false
+	}
+	if !strongIsWhite {
+		corner >>= 56
+	}
+	bishops := b.pieceBitboard(Bishop, strongIsWhite)
+	if corner&darkSquares != 0 && bishops&darkSquares != 0 || corner&darkSquares == 0 && bishops&^darkSquares != 0 {
+		return 0, false
+	}
+	_, weakKing := b.kings(strongIsWhite)
+	if squareDistance(weakKing, bits.TrailingZeros64(corner)) > 1 {
+		return 0, false
+	}
+	return scoreDraw, true
+}
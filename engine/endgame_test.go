@@ -0,0 +1,96 @@
+package engine
+
+import (
+	"math/bits"
+	"testing"
+)
+
+func TestEndgame(t *testing.T) {
+	tests := []struct {
+		fen, name string
+	}{
+		{"8/8/8/4k3/8/8/4P3/4K3 w - - 0 1", "KPvK"},
+		{"8/8/8/4k3/8/8/4p3/4K3 w - - 0 1", "KPvK"},
+		{"8/8/8/4k3/8/8/8/2BNK3 w - - 0 1", "KBNvK"},
+		{"8/8/8/4k3/8/8/8/4K3 w - - 0 1", "KvK"},
+		{"8/8/8/3nk3/8/8/8/4KB2 w - - 0 1", "KBvKN"},
+		{"8/8/8/3nk3/8/8/8/3NK3 w - - 0 1", "KNvKN"},
+		{"8/8/8/4k3/8/8/8/3QK3 b - - 0 1", "KQvK"},
+		{"8/8/8/4k3/8/8/8/2RRK3 w - - 0 1", "KRRvK"},
+		{"8/8/8/3rk3/8/8/8/4K3 w - - 0 1", "KRvK"},
+		{"8/8/8/4k3/8/8/P7/B3K3 w - - 0 1", "KBPvK"},
+		{"8/p7/8/4k3/8/8/P7/B3K3 w - - 0 1", ""},
+		{"8/8/8/4k3/8/8/8/3QKR2 w - - 0 1", "KQRvK"},
+		{"8/8/8/3qk3/8/8/8/3QK3 w - - 0 1", ""},
+		{"8/8/8/4k3/8/8/4P3/3RK3 w - - 0 1", ""},
+		{"8/8/8/2bnk3/8/8/8/4K3 w - - 0 1", "KBNvK"},
+		{"8/8/8/3nk3/8/8/8/2NNK3 w - - 0 1", ""},
+		{startPosition, ""},
+	}
+	for _, tt := range tests {
+		if got := testBoard(tt.fen).endgameName(); got != tt.name {
+			t.Errorf("%s: endgame %q, want %q", tt.fen, got, tt.name)
+		}
+	}
+}
+
+func TestNameMaterialKey(t *testing.T) {
+	for _, fen := range []string{startPosition, "8/8/8/3nk3/8/8/PPP5/1BNRK3 w - - 0 1"} {
+		b := testBoard(fen)
+		for _, isWhite := range []bool{true, false} {
+			if got, want := nameMaterialKey(b.materialString(isWhite)), b.materialKey(isWhite); got != want {
+				t.Errorf("%s: key %x from the name, %x from the board", fen, got, want)
+			}
+		}
+	}
+}
+
+func TestEvalDoesNotAllocate(t *testing.T) {
+	for _, fen := range append([]string{"8/8/8/4k3/8/8/4P3/4K3 w - - 0 1"}, evalTestFens...) {
+		b := testBoard(fen)
+		if allocs := testing.AllocsPerRun(100, func() { b.eval(true) }); allocs != 0 {
+			t.Errorf("%s: eval allocates %v times", fen, allocs)
+		}
+	}
+}
+
+func TestKPKBitbase(t *testing.T) {
+	wins := 0
+	for _, word := range kpkBits {
+		wins += bits.OnesCount64(word)
+	}
+	if wins != 111282 {
+		t.Errorf("bitbase has %d wins, want 111282", wins)
+	}
+
+	tests := []struct {
+		fen string
+		win bool
+	}{
+		// the king on the sixth rank in front of its pawn wins whoever moves
+		{"4k3/8/4K3/4P3/8/8/8/8 w - - 0 1", true},
+		{"4k3/8/4K3/4P3/8/8/8/8 b - - 0 1", true},
+		// with the pawn on the sixth and the king behind it, the defending king holds
+		{"4k3/8/4P3/4K3/8/8/8/8 w - - 0 1", false},
+		{"4k3/8/4P3/4K3/8/8/8/8 b - - 0 1", false},
+		// with the pawn further back the opposition decides
+		{"4k3/8/8/4K3/4P3/8/8/8 w - - 0 1", true},
+		// the same for black, on the other wing
+		{"8/8/8/8/2p5/2k5/8/2K5 b - - 0 1", true},
+		{"8/8/8/8/2k5/2p5/8/2K5 b - - 0 1", false},
+		// a rook pawn with the defending king in the corner
+		{"k7/8/8/8/8/8/P7/K7 w - - 0 1", false},
+		// the pawn runs before the king catches it
+		{"8/7k/8/8/P7/8/8/K7 w - - 0 1", true},
+	}
+	for _, tt := range tests {
+		g := parseGame(tt.fen)
+		score, ok := g.board.evalEndgame(g.whiteToMove)
+		if !ok {
+			t.Fatalf("%s: not evaluated as KPK", tt.fen)
+		}
+		if win := score >= scoreKnownWin || score <= -scoreKnownWin; win != tt.win {
+			t.Errorf("%s: score %d, want win %v", tt.fen, score, tt.win)
+		}
+	}
+}
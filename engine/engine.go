@@ -1,19 +1,15 @@
 package engine
 
 import (
+	"encoding/json"
 	"fmt"
-	"math"
 	"math/rand"
+	"strconv"
 	"strings"
 )
 
 var searchDepth int = 6
 
-var mainBoard Board
-
-// better design to also store the result of getalllegalmoves and keep updating as this func is called many times.
-var moveHistory []string
-
 func engine() (frEng chan string, toEng chan string) {
 	tell("Hello from engine")
 	frEng = make(chan string)
@@ -32,34 +28,132 @@ func engine() (frEng chan string, toEng chan string) {
 			case "w":
 				var b Board
 				b.Initialize()
-				mainBoard = b
-				mainBoard.PrintBoard(true, 0)
+				mainGame = newGame(b, true)
+				mainGame.board.PrintBoard(true, 0)
 				frEng <- "new board initialized, you are playing white"
 			case "b":
 				var b Board
 				b.Initialize()
-				mainBoard = b
+				mainGame = newGame(b, true)
 				frEng <- "new board initialized, you are playing black"
-				mainBoard.startWhite()
+				mainGame.startWhite()
 			case "random":
 				var b Board
 				b.Initialize()
 				randomNumber := rand.Intn(2)
 				randomBool := randomNumber == 1
-				mainBoard = b
-				mainBoard.PrintBoard(randomBool, 0)
+				mainGame = newGame(b, true)
+				mainGame.board.PrintBoard(randomBool, 0)
 				frEng <- "new board initialized, you are playing "
 			case "eval":
-				mainBoard.showEvalScore()
+				mainGame.board.showEvalScore(mainGame.whiteToMove)
+			case "eval json":
+				trace, _ := json.Marshal(mainGame.board.evalTrace(mainGame.whiteToMove))
+				frEng <- string(trace)
+			case "result":
+				if result := mainGame.resultMessage(); result != "" {
+					frEng <- result
+				} else {
+					frEng <- "game in progress (" + Ongoing.String() + ")"
+				}
+			case "resign":
+				// the player resigns on their own turn
+				mainGame.Resign(mainGame.whiteToMove)
+				frEng <- mainGame.resultMessage()
+			case "undo":
+				frEng <- mainGame.handleTakeback("1", false)
+			case "redo":
+				frEng <- mainGame.handleTakeback("1", true)
 			default:
 				if strings.HasPrefix(cmd, "fen ") {
 					otherString := strings.TrimPrefix(cmd, "fen ")
-					mainBoard = parse(otherString)
-					mainBoard.PrintBoard(true, 0)
+					g, err := parsePosition(otherString)
+					if err != nil {
+						tell("info string " + err.Error())
+					}
+					mainGame = g
+					mainGame.board.PrintBoard(true, 0)
 
+				} else if strings.HasPrefix(cmd, "evalfile ") {
+					path := strings.TrimPrefix(cmd, "evalfile ")
+					if path == "" || path == "<empty>" {
+						SetEvalParams(DefaultEvalParams())
+						frEng <- "info string using built-in eval params"
+					} else if params, err := LoadEvalParams(path); err != nil {
+						frEng <- "info string " + err.Error()
+					} else {
+						SetEvalParams(params)
+						frEng <- "info string eval params loaded from " + path
+					}
+				} else if strings.HasPrefix(cmd, "nnuefile ") {
+					path := strings.TrimPrefix(cmd, "nnuefile ")
+					if path == "" || path == "<empty>" {
+						SetEvaluator(HandCraftedEval{})
+						frEng <- "info string using hand-crafted eval"
+					} else if net, err := LoadNetwork(path); err != nil {
+						frEng <- "info string " + err.Error()
+					} else {
+						SetEvaluator(net)
+						frEng <- "info string network loaded from " + path
+					}
+				} else if strings.HasPrefix(cmd, "ownbook ") {
+					ownBook = strings.TrimPrefix(cmd, "ownbook ") == "true"
+				} else if strings.HasPrefix(cmd, "bookfile ") {
+					path := strings.TrimPrefix(cmd, "bookfile ")
+					if path == "" || path == "<empty>" {
+						openingBook = nil
+						frEng <- "info string no book"
+					} else if book, err := LoadBook(path); err != nil {
+						frEng <- "info string " + err.Error()
+					} else {
+						openingBook = book
+						frEng <- fmt.Sprintf("info string book loaded from %s, %d entries", path, len(book.entries))
+					}
+				} else if strings.HasPrefix(cmd, "tablebasefiles ") {
+					paths := strings.TrimPrefix(cmd, "tablebasefiles ")
+					if paths == "" || paths == "<empty>" {
+						SetTablebase(nil)
+						frEng <- "info string no tablebases"
+					} else if tables, err := LoadRetroTables(paths); err != nil {
+						frEng <- "info string " + err.Error()
+					} else {
+						SetTablebase(tables)
+						frEng <- fmt.Sprintf("info string loaded %d tables, up to %d pieces", len(tables), tables.MaxPieces())
+					}
+				} else if strings.HasPrefix(cmd, "see ") {
+					otherString := strings.TrimPrefix(cmd, "see ")
+					if move, err := mainGame.parseMove(otherString); err != nil {
+						frEng <- err.Error()
+					} else {
+						frEng <- fmt.Sprintf("see %v: %v", otherString, mainGame.board.see(move))
+					}
+				} else if strings.HasPrefix(cmd, "save ") {
+					path := strings.TrimPrefix(cmd, "save ")
+					if err := mainGame.savePGN(path); err != nil {
+						frEng <- "info string " + err.Error()
+					} else {
+						frEng <- "info string game saved to " + path
+					}
+				} else if strings.HasPrefix(cmd, "load ") {
+					path := strings.TrimPrefix(cmd, "load ")
+					if g, err := loadPGN(path); len(g.history) == 0 {
+						frEng <- "info string " + err.Error()
+					} else {
+						// the game is set up as far as it could be played, to step through with undo and redo
+						if err != nil {
+							tell("info string " + err.Error())
+						}
+						mainGame = g
+						mainGame.printBoard()
+						frEng <- fmt.Sprintf("info string loaded %d moves from %s", len(mainGame.undoStack), path)
+					}
+				} else if strings.HasPrefix(cmd, "undo ") {
+					frEng <- mainGame.handleTakeback(strings.TrimPrefix(cmd, "undo "), false)
+				} else if strings.HasPrefix(cmd, "redo ") {
+					frEng <- mainGame.handleTakeback(strings.TrimPrefix(cmd, "redo "), true)
 				} else if strings.HasPrefix(cmd, "move ") {
 					otherString := strings.TrimPrefix(cmd, "move ")
-					responseMove := mainBoard.handleMove(otherString)
+					responseMove := mainGame.handleMove(otherString)
 					frEng <- responseMove
 				}
 			}
@@ -69,86 +163,106 @@ func engine() (frEng chan string, toEng chan string) {
 	return frEng, toEng
 }
 
-func (b *Board) handleMove(move string) string {
-
-	if b.isCastleMove(move) {
-		submove := castleMoveInfo[move].KingMove[1:3]
-		colour := b.getColour(b.notationToPos(submove))
-		fmt.Println(colour, submove)
-		fmt.Println(castleMoveInfo[move].CanCastle)
-		if b.canCastle(colour, move) {
-			responseMove := b.handleCastleMove(move)
-			castleMoveInfo[move].CanCastle = false
-			return responseMove
-		} else {
-			return "Illegal Move"
-		}
-	}
-
-	piece, initPos64, finalPos64 := b.notationToMove(move)
-	colour := b.getColour(initPos64)
+func (g *Game) handleMove(move string) string {
+	b := &g.board
 
-	if !b.isMoveLegal(colour, initPos64, finalPos64) {
-		return "Illegal Move"
+	if result := g.resultMessage(); result != "" {
+		return "Game over: " + result
 	}
-	_, _ = b.makeMove(initPos64, finalPos64, colour, piece)
-	updateCastleMoveInfo(move)
-	b.PrintBoard(colour, finalPos64)
-
-	responseMove := b.getResponseMove(colour)
-	updateCastleMoveInfo(responseMove)
-	// Edge case : when engine plays king in one of its position children and goes back, he can castle cause we are updating here. So, handle that case.
-	// First of all enable for engine the castle move.
-	return responseMove
-}
-
-func (b *Board) getResponseMove(colour bool) string {
-	_, bestMove := b.alphaBetaMiniMax(!colour, math.Inf(-1), math.Inf(1), searchDepth)
-	var pieceType PieceType = b.getPieceType(bestMove[0])
-	wasPieceCaptured, _ := b.makeMove(bestMove[0], bestMove[1], !colour, pieceType)
 
-	b.PrintBoard(colour, bestMove[1])
-
-	if b.isCheckmate(colour) {
-		fmt.Println("Bot Wins!")
-	} else if b.isCheckmate(!colour) {
-		fmt.Println("Bot Loses!")
+	m, err := g.parseMove(move)
+	if err != nil {
+		return err.Error()
+	}
+	colour := g.whiteToMove
+	g.play(m)
+	b.PrintBoard(colour, m.to())
+	if result := g.resultMessage(); result != "" {
+		fmt.Println(result)
+		return result
 	}
 
-	responseMove := b.moveToNotation(colour, bestMove[0], bestMove[1], pieceType, wasPieceCaptured)
-	return responseMove
+	return g.getResponseMove(colour)
 }
 
-func (b *Board) handleCastleMove(move string) string {
-
-	kingpiece, kinginitPos64, kingfinalPos64 := b.notationToMove(castleMoveInfo[move].KingMove)
-	colour := b.getColour(kinginitPos64)
+// handleTakeback takes back, or with redo plays again, the given number of plies.
+// Against the engine "undo 2" takes back the player's last move and the reply.
+func (g *Game) handleTakeback(plies string, redo bool) string {
+	n, err := strconv.Atoi(strings.TrimSpace(plies))
+	if err != nil || n < 1 {
+		return "invalid number of moves " + plies
+	}
+	step, verb := g.Undo, "took back"
+	if redo {
+		step, verb = g.Redo, "replayed"
+	}
+	var moves []string
+	for ; n > 0; n-- {
+		m, ok := step()
+		if !ok {
+			break
+		}
+		moves = append(moves, m.String())
+	}
+	if len(moves) == 0 {
+		if redo {
+			return "nothing to redo"
+		}
+		return "nothing to undo"
+	}
+	g.printBoard()
+	return verb + " " + strings.Join(moves, " ")
+}
 
-	_, _ = b.makeMove(kinginitPos64, kingfinalPos64, colour, kingpiece)
-	moveHistory = append(moveHistory, move+" ")
+// printBoard shows the board from the side of the player to move, with the square of
+// the last move lit up.
+func (g *Game) printBoard() {
+	var last uint64
+	if m := g.lastMove(); m != noMove {
+		last = m.to()
+	}
+	g.board.PrintBoard(g.whiteToMove, last)
+}
 
-	rookpiece, rookinitPos64, rookfinalPos64 := b.notationToMove(castleMoveInfo[move].RookMove)
+func (g *Game) getResponseMove(colour bool) string {
+	b := &g.board
+	var bestMove Move
+	var notes pgnNotes
+	if move, ok := g.ownBookMove(); ok {
+		bestMove = move
+		notes.comment = "book"
+		tell("info string book move " + move.String())
+	} else if move, ok := g.tablebaseMove(); ok {
+		bestMove = move
+		notes.comment = "tablebase"
+		tell(fmt.Sprintf("info string tablebase move %s tbhits %d", move, tbHits))
+	} else {
+		var score Score
+		startSearch(g.history)
+		score, bestMove = b.alphaBetaMiniMax(!colour, -scoreInfinite, scoreInfinite, searchDepth, g.halfmoveClock)
+		notes.eval = pgnEval(score)
+		tell(fmt.Sprintf("info depth %d score %s tbhits %d", searchDepth, uciScore(score, !colour), tbHits))
+	}
+	responseMove := g.formatSAN(bestMove)
+	g.play(bestMove)
+	g.undoStack[len(g.undoStack)-1].byEngine = true
+	g.undoStack[len(g.undoStack)-1].notes = notes
 
-	_, _ = b.makeMove(rookinitPos64, rookfinalPos64, colour, rookpiece)
-	moveHistory = append(moveHistory, move+" ")
+	b.PrintBoard(colour, bestMove.to())
 
-	b.PrintBoard(colour, rookfinalPos64)
+	if result := g.resultMessage(); result != "" {
+		fmt.Println(result)
+	}
 
-	responseMove := b.getResponseMove(colour)
 	return responseMove
 }
 
-func updateCastleMoveInfo(move string) {
-
-	if move[0] == 'K' {
-		castleMoveInfo["O-O"].CanCastle = false
-		castleMoveInfo["O-O-O"].CanCastle = false
+// ownBookMove returns a move from the opening book when the engine is set to use one.
+func (g *Game) ownBookMove() (Move, bool) {
+	if !ownBook || openingBook == nil {
+		return noMove, false
 	}
-	if move[0] == 'k' {
-		castleMoveInfo["o-o"].CanCastle = false
-		castleMoveInfo["o-o-o"].CanCastle = false
-	}
-
+	return g.bookMove(openingBook)
 }
 
 // func (b *Board) makeUserMove(move string) bool {
@@ -172,15 +286,15 @@ func updateCastleMoveInfo(move string) {
 // 	b.Print(false)
 // }
 
-func (b *Board) startWhite() string {
-	b.PrintBoard(false, 0)
-	responseMove := b.getResponseMove(false)
+func (g *Game) startWhite() string {
+	g.board.PrintBoard(false, 0)
+	responseMove := g.getResponseMove(false)
 	return responseMove
 }
 
-func (b *Board) showEvalScore() {
-	materialScore, positonalScore := b.eval()
-	fmt.Printf("Material score: %v\n", materialScore)
-	fmt.Printf("Position score: %v\n", positonalScore)
-	fmt.Printf("Mobility score: %v\n", b.evalMobility())
+func (b *Board) showEvalScore(whiteToMove bool) {
+	fmt.Print(b.evalTrace(whiteToMove))
+	if _, ok := evaluator.(HandCraftedEval); !ok {
+		fmt.Printf("%s evaluation: %+.2f (white side)\n", evaluator.Name(), float64(evaluator.Evaluate(b, whiteToMove))/100)
+	}
 }
@@ -1,7 +1,10 @@
 package engine
 
+import "math/bits"
+
+// default piece-square tables, see DefaultEvalParams
 var (
-	pawn_pos_weight = [64]float64{
+	pawn_pos_weight = [64]int{
 		0, 0, 0, 0, 0, 0, 0, 0,
 		50, 50, 50, 50, 50, 50, 50, 50,
 		10, 10, 20, 30, 30, 20, 10, 10,
@@ -11,7 +14,7 @@ var (
 		5, 10, 10, -20, -20, 10, 10, 5,
 		0, 0, 0, 0, 0, 0, 0, 0}
 
-	pawn_pos_weight_eg = [64]float64{
+	pawn_pos_weight_eg = [64]int{
 		0, 0, 0, 0, 0, 0, 0, 0,
 		80, 80, 80, 80, 80, 80, 80, 80,
 		50, 50, 50, 50, 50, 50, 50, 50,
@@ -21,7 +24,7 @@ var (
 		10, 10, 10, 10, 10, 10, 10, 10,
 		0, 0, 0, 0, 0, 0, 0, 0}
 
-	knight_pos_weight = [64]float64{-50, -40, -30, -30, -30, -30, -40, -50,
+	knight_pos_weight = [64]int{-50, -40, -30, -30, -30, -30, -40, -50,
 		-40, -20, 0, 0, 0, 0, -20, -40,
 		-30, 0, 10, 15, 15, 10, 0, -30,
 		-30, 5, 15, 20, 20, 15, 5, -30,
@@ -30,7 +33,16 @@ var (
 		-40, -20, 0, 5, 5, 0, -20, -40,
 		-50, -40, -30, -30, -30, -30, -40, -50}
 
-	bishop_pos_weight = [64]float64{-20, -10, -10, -10, -10, -10, -10, -20,
+	knight_pos_weight_eg = [64]int{-50, -30, -20, -20, -20, -20, -30, -50,
+		-30, -10, 0, 5, 5, 0, -10, -30,
+		-20, 0, 10, 15, 15, 10, 0, -20,
+		-20, 5, 15, 20, 20, 15, 5, -20,
+		-20, 5, 15, 20, 20, 15, 5, -20,
+		-20, 0, 10, 15, 15, 10, 0, -20,
+		-30, -10, 0, 5, 5, 0, -10, -30,
+		-50, -30, -20, -20, -20, -20, -30, -50}
+
+	bishop_pos_weight = [64]int{-20, -10, -10, -10, -10, -10, -10, -20,
 		-10, 0, 0, 0, 0, 0, 0, -10,
 		-10, 0, 5, 10, 10, 5, 0, -10,
 		-10, 5, 5, 10, 10, 5, 5, -10,
@@ -39,7 +51,16 @@ var (
 		-10, 5, 0, 0, 0, 0, 5, -10,
 		-20, -10, -10, -10, -10, -10, -10, -20}
 
-	rook_pos_weight = [64]float64{0, 0, 0, 0, 0, 0, 0, 0,
+	bishop_pos_weight_eg = [64]int{-15, -10, -10, -10, -10, -10, -10, -15,
+		-10, 0, 0, 0, 0, 0, 0, -10,
+		-10, 0, 5, 5, 5, 5, 0, -10,
+		-10, 0, 5, 10, 10, 5, 0, -10,
+		-10, 0, 5, 10, 10, 5, 0, -10,
+		-10, 0, 5, 5, 5, 5, 0, -10,
+		-10, 0, 0, 0, 0, 0, 0, -10,
+		-15, -10, -10, -10, -10, -10, -10, -15}
+
+	rook_pos_weight = [64]int{0, 0, 0, 0, 0, 0, 0, 0,
 		5, 10, 10, 10, 10, 10, 10, 5,
 		-5, 0, 0, 0, 0, 0, 0, -5,
 		-5, 0, 0, 0, 0, 0, 0, -5,
@@ -48,7 +69,16 @@ var (
 		-5, 0, 0, 0, 0, 0, 0, -5,
 		0, 0, 0, 5, 5, 0, 0, 0}
 
-	queen_pos_weight = [64]float64{-20, -10, -10, -5, -5, -10, -10, -20,
+	rook_pos_weight_eg = [64]int{5, 5, 5, 5, 5, 5, 5, 5,
+		10, 10, 10, 10, 10, 10, 10, 10,
+		0, 0, 0, 0, 0, 0, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 0,
+		-5, 0, 0, 0, 0, 0, 0, -5}
+
+	queen_pos_weight = [64]int{-20, -10, -10, -5, -5, -10, -10, -20,
 		-10, 0, 0, 0, 0, 0, 0, -10,
 		-10, 0, 5, 5, 5, 5, 0, -10,
 		-5, 0, 5, 5, 5, 5, 0, -5,
@@ -57,7 +87,16 @@ var (
 		-10, 0, 5, 0, 0, 0, 0, -10,
 		-20, -10, -10, -5, -5, -10, -10, -20}
 
-	king_pos_weight = [64]float64{-30, -40, -40, -50, -50, -40, -40, -30,
+	queen_pos_weight_eg = [64]int{-30, -20, -10, -10, -10, -10, -20, -30,
+		-20, -10, 0, 5, 5, 0, -10, -20,
+		-10, 0, 10, 15, 15, 10, 0, -10,
+		-10, 5, 15, 20, 20, 15, 5, -10,
+		-10, 5, 15, 20, 20, 15, 5, -10,
+		-10, 0, 10, 15, 15, 10, 0, -10,
+		-20, -10, 0, 5, 5, 0, -10, -20,
+		-30, -20, -10, -10, -10, -10, -20, -30}
+
+	king_pos_weight = [64]int{-30, -40, -40, -50, -50, -40, -40, -30,
 		-30, -40, -40, -50, -50, -40, -40, -30,
 		-30, -40, -40, -50, -50, -40, -40, -30,
 		-30, -40, -40, -50, -50, -40, -40, -30,
@@ -66,7 +105,7 @@ var (
 		20, 20, 0, 0, 0, 0, 20, 20,
 		20, 30, 10, 0, 0, 10, 30, 20}
 
-	king_pos_weight_eg = [64]float64{-50, -40, -30, -20, -20, -30, -40, -50,
+	king_pos_weight_eg = [64]int{-50, -40, -30, -20, -20, -30, -40, -50,
 		-30, -20, -10, 0, 0, -10, -20, -30,
 		-30, -10, 20, 30, 30, 20, -10, -30,
 		-30, -10, 30, 40, 40, 30, -10, -30,
@@ -76,100 +115,181 @@ var (
 		-50, -30, -30, -30, -30, -30, -30, -50}
 )
 
-func (b *Board) eval() (float64, float64) {
-	var endgameT float64 = 0
-	var materialScore float64 = b.evalMaterialValues()
-	var pstScore float64 = b.evalPieceSquareTables(endgameT)
-	return materialScore, pstScore
+// phase weights of the non-pawn pieces; with all of them on the board the phase is totalPhase
+const (
+	knightPhase = 1
+	bishopPhase = 1
+	rookPhase   = 2
+	queenPhase  = 4
+	totalPhase  = 4*knightPhase + 4*bishopPhase + 4*rookPhase + 2*queenPhase
+)
+
+// gamePhase measures how much non-pawn material is left, from totalPhase in the
+// opening down to 0 when only kings and pawns remain.
+func (b *Board) gamePhase() int {
+	count := func(idx int) int {
+		return bits.OnesCount64(b.pieces[whiteIndex][idx] | b.pieces[blackIndex][idx])
+	}
+	phase := knightPhase*count(knightIndex) + bishopPhase*count(bishopIndex) +
+		rookPhase*count(rookIndex) + queenPhase*count(queenIndex)
+	// promotions can push the count above the starting material
+	return min(phase, totalPhase)
 }
 
-func (b *Board) evalMaterialValues() float64 {
-	var score float64 = 0
-	for i := 0; i < 64; i++ {
-		var cur_pos uint64 = 1 << uint64(i)
-		if b.whitePawns&cur_pos != 0 {
-			score += pawn_wt
-		} else if b.whiteKnights&cur_pos != 0 {
-			score += knight_wt
-		} else if b.whiteBishops&cur_pos != 0 {
-			score += bishop_wt
-		} else if b.whiteRooks&cur_pos != 0 {
-			score += rook_wt
-		} else if b.whiteQueens&cur_pos != 0 {
-			score += queen_wt
-		} else if b.whiteKing&cur_pos != 0 {
-			score += king_wt
-		} else if b.blackPawns&cur_pos != 0 {
-			score -= pawn_wt
-		} else if b.blackKnights&cur_pos != 0 {
-			score -= knight_wt
-		} else if b.blackBishops&cur_pos != 0 {
-			score -= bishop_wt
-		} else if b.blackRooks&cur_pos != 0 {
-			score -= rook_wt
-		} else if b.blackQueens&cur_pos != 0 {
-			score -= queen_wt
-		} else if b.blackKing&cur_pos != 0 {
-			score -= king_wt
-		}
+// taper blends a midgame and an endgame value by the game phase, giving the full
+// midgame value at totalPhase and the full endgame value at 0.
+func taper(mg, eg int, phase int) Score {
+	return Score((mg*phase + eg*(totalPhase-phase)) / totalPhase)
+}
+
+// evaluation terms, each scored separately for white and black
+const (
+	termMaterial = iota
+	termPieceSquares
+	termPawns
+	termKingSafety
+	termActivity
+	numEvalTerms
+)
+
+var evalTermNames = [numEvalTerms]string{"Material", "Piece squares", "Pawns", "King safety", "Activity"}
+
+// evalTerms computes every evaluation term for white (index 0) and black (index 1).
+func (b *Board) evalTerms() [numEvalTerms][2]scorePair {
+	var terms [numEvalTerms][2]scorePair
+	terms[termMaterial][0], terms[termMaterial][1] = b.evalMaterialValues()
+	terms[termPieceSquares][0], terms[termPieceSquares][1] = b.evalPieceSquareTables()
+	terms[termPawns][0], terms[termPawns][1] = b.evalPawnStructure()
+	terms[termKingSafety][0], terms[termKingSafety][1] = b.evalKingSafety()
+	terms[termActivity][0], terms[termActivity][1] = b.evalPieceActivity()
+	return terms
+}
+
+// eval returns the score of the board in centipawns from white's point of view: the sum
+// of all terms for white minus those for black, blended by the game phase. Endgames
+// with a known result or plan are scored by their own evaluation instead. Material,
+// piece squares and phase come from the board's running sums, so only the terms that
+// depend on how the pieces stand towards each other are worked out here.
+func (b *Board) eval(whiteToMove bool) Score {
+	if score, ok := b.evalEndgame(whiteToMove); ok {
+		return score
+	}
+	if b.sums.version != evalParamsVersion {
+		b.sums = b.computeEvalSums()
+	}
+	total := b.sums.material[whiteIndex].sub(b.sums.material[blackIndex]).
+		add(b.sums.pst[whiteIndex]).sub(b.sums.pst[blackIndex])
+	for _, term := range []func(*Board) (scorePair, scorePair){
+		(*Board).evalPawnStructure, (*Board).evalKingSafety, (*Board).evalPieceActivity,
+	} {
+		white, black := term(b)
+		total = total.add(white).sub(black)
 	}
-	return score
+	return taper(total.mg, total.eg, min(b.sums.phase, totalPhase))
 }
-func (b *Board) evalPieceSquareTables(endgameT float64) float64 {
-	var score float64 = 0
-	for i := 0; i < 64; i++ {
-		var cur_pos uint64 = 1 << uint64(i)
-		if b.whitePawns&cur_pos != 0 {
-			score += pawn_pos_weight[63-i]*(1-endgameT) + pawn_pos_weight_eg[63-i]*endgameT
-		} else if b.whiteKnights&cur_pos != 0 {
-			score += knight_pos_weight[63-i]
-		} else if b.whiteBishops&cur_pos != 0 {
-			score += bishop_pos_weight[63-i]
-		} else if b.whiteRooks&cur_pos != 0 {
-			score += rook_pos_weight[63-i]
-		} else if b.whiteQueens&cur_pos != 0 {
-			score += queen_pos_weight[63-i]
-		} else if b.whiteKing&cur_pos != 0 {
-			score += king_pos_weight[63-i]*(1-endgameT) + king_pos_weight_eg[63-i]*endgameT
-		} else if b.blackPawns&cur_pos != 0 {
-			score -= pawn_pos_weight[i]*(1-endgameT) + pawn_pos_weight_eg[i]*endgameT
-		} else if b.blackKnights&cur_pos != 0 {
-			score -= knight_pos_weight[i]
-		} else if b.blackBishops&cur_pos != 0 {
-			score -= bishop_pos_weight[i]
-		} else if b.blackRooks&cur_pos != 0 {
-			score -= rook_pos_weight[i]
-		} else if b.blackQueens&cur_pos != 0 {
-			score -= queen_pos_weight[i]
-		} else if b.blackKing&cur_pos != 0 {
-			score -= king_pos_weight[i]*(1-endgameT) + king_pos_weight_eg[i]*endgameT
-		}
+
+// evalSums are the material, piece-square and phase totals of a board. Once computed
+// they are updated by movePiece as pieces come and go, so they need not be counted
+// again at every leaf. They hold only while the weights they were computed with are
+// in use.
+type evalSums struct {
+	version  uint64       // evalParamsVersion they were computed with, 0 before
+	material [2]scorePair // by colour, kings not counted
+	pst      [2]scorePair // by colour
+	phase    int          // not capped at totalPhase, promotions can push it above
+}
+
+// computeEvalSums works the running sums of the board out from scratch.
+func (b *Board) computeEvalSums() evalSums {
+	s := evalSums{version: evalParamsVersion}
+	s.material[whiteIndex], s.material[blackIndex] = b.evalMaterialValues()
+	s.pst[whiteIndex], s.pst[blackIndex] = b.evalPieceSquareTables()
+	for idx, pieceType := range pieceTypes {
+		s.phase += piecePhase(pieceType) * bits.OnesCount64(b.kindBitboard(idx))
 	}
-	return score
+	return s
 }
-func (b *Board) evalMobility() float64 {
-	var score float64 = 0
-	for _, move := range b.getAllLegalMoves(true) {
-		isCastleMove, _ := checkMoveIsCastle(move)
-		if isCastleMove {
-			continue
-		}
-		for i := 0; i < 64; i++ {
-			if move[1]&(1<<i) != 0 {
-				score += 1
-			}
+
+// update moves a piece in the sums. Either square may be 0, for a piece that is added
+// to or removed from the board.
+func (s *evalSums) update(initPos, finalPos uint64, pieceType PieceType, isWhite bool) {
+	colour := colourIndex(isWhite)
+	tables := evalParams.PieceSquareTables.of(pieceType)
+	if initPos != 0 {
+		s.pst[colour] = s.pst[colour].sub(pst(tables, pstIndex(initPos, isWhite)))
+	}
+	if finalPos != 0 {
+		s.pst[colour] = s.pst[colour].add(pst(tables, pstIndex(finalPos, isWhite)))
+	}
+	if pieceType == King || (initPos != 0) == (finalPos != 0) {
+		return
+	}
+	value := evalParams.PieceValues.of(pieceType)
+	phase := piecePhase(pieceType)
+	if finalPos == 0 {
+		value, phase = -value, -phase
+	}
+	s.material[colour] = s.material[colour].add(scorePair{value, value})
+	s.phase += phase
+}
+
+// piecePhase is how much a piece counts towards the game phase.
+func piecePhase(pieceType PieceType) int {
+	switch pieceType {
+	case Knight:
+		return knightPhase
+	case Bishop:
+		return bishopPhase
+	case Rook:
+		return rookPhase
+	case Queen:
+		return queenPhase
+	}
+	return 0
+}
+
+// evalMaterialValues counts each side's material. Both sides always have a king, so kings are not counted.
+func (b *Board) evalMaterialValues() (scorePair, scorePair) {
+	values := &evalParams.PieceValues
+	var material [2]scorePair
+	for colour := range b.pieces {
+		value := 0
+		for idx, pieceType := range pieceTypes[:kingIndex] {
+			value += values.of(pieceType) * bits.OnesCount64(b.pieces[colour][idx])
 		}
+		material[colour] = scorePair{value, value}
 	}
-	for _, move := range b.getAllLegalMoves(false) {
-		isCastleMove, _ := checkMoveIsCastle(move)
-		if isCastleMove {
-			continue
+	return material[whiteIndex], material[blackIndex]
+}
+
+// pst looks up a square in a piece's midgame and endgame tables.
+func pst(tables *PieceTables, idx int) scorePair {
+	return scorePair{tables.Mg[idx], tables.Eg[idx]}
+}
+
+// evalPieceSquareTables scores each side's piece placement with the midgame and endgame
+// tables.
+func (b *Board) evalPieceSquareTables() (scorePair, scorePair) {
+	var white, black scorePair
+	for idx, pieceType := range pieceTypes {
+		tables := evalParams.PieceSquareTables.of(pieceType)
+		for pieces := b.pieces[whiteIndex][idx]; pieces != 0; pieces &= pieces - 1 {
+			white = white.add(pst(tables, pstIndex(pieces&-pieces, true)))
 		}
-		for i := 0; i < 64; i++ {
-			if move[1]&(1<<i) != 0 {
-				score -= 1
-			}
+		for pieces := b.pieces[blackIndex][idx]; pieces != 0; pieces &= pieces - 1 {
+			black = black.add(pst(tables, pstIndex(pieces&-pieces, false)))
 		}
 	}
-	return score
+	return white, black
+}
+
+// pstIndex returns the entry of a piece-square table for a piece on pos. The tables
+// are written from white's side with a8 first, so bit i maps to entry 63-i for white
+// and, flipped vertically, to entry i^7 for black.
+func pstIndex(pos uint64, isWhite bool) int {
+	sq := bits.TrailingZeros64(pos)
+	if isWhite {
+		return 63 - sq
+	}
+	return sq ^ 7
 }
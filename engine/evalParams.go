@@ -0,0 +1,214 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// EvalParams holds every weight the evaluation uses, so that they can be tuned and
+// loaded from a file instead of being compiled in. All values are in centipawns, and
+// the pairs are [midgame, endgame].
+type EvalParams struct {
+	PieceValues       PieceWeights      `json:"pieceValues"`
+	PieceSquareTables PieceSquareTables `json:"pieceSquareTables"`
+
+	DoubledPawn  scorePair    `json:"doubledPawn"`
+	IsolatedPawn scorePair    `json:"isolatedPawn"`
+	BackwardPawn scorePair    `json:"backwardPawn"`
+	PassedPawn   [8]scorePair `json:"passedPawn"` // by rank counted from the pawn's own side
+
+	KingAttackWeight     PieceWeights `json:"kingAttackWeight"` // per attacked king zone square
+	MaxKingDanger        int          `json:"maxKingDanger"`
+	PawnShield           [2]int       `json:"pawnShield"` // shield pawn one or two ranks in front of the king
+	MissingShield        int          `json:"missingShield"`
+	PawnStorm            [4]int       `json:"pawnStorm"` // enemy pawn this many ranks in front of the king
+	SemiOpenFileNearKing int          `json:"semiOpenFileNearKing"`
+	OpenFileNearKing     int          `json:"openFileNearKing"`
+
+	Mobility         PieceScores  `json:"mobility"`        // per square above the average
+	MobilityAverage  PieceWeights `json:"mobilityAverage"` // squares a piece usually reaches
+	BishopPair       scorePair    `json:"bishopPair"`
+	RookOpenFile     scorePair    `json:"rookOpenFile"`
+	RookSemiOpenFile scorePair    `json:"rookSemiOpenFile"`
+	RookOnSeventh    scorePair    `json:"rookOnSeventh"`
+	KnightOutpost    scorePair    `json:"knightOutpost"`
+	BishopOutpost    scorePair    `json:"bishopOutpost"`
+	TrappedBishop    scorePair    `json:"trappedBishop"`
+	TrappedRook      scorePair    `json:"trappedRook"`
+}
+
+// PieceWeights holds one value per piece type.
+type PieceWeights struct {
+	Pawn   int `json:"pawn"`
+	Knight int `json:"knight"`
+	Bishop int `json:"bishop"`
+	Rook   int `json:"rook"`
+	Queen  int `json:"queen"`
+	King   int `json:"king"`
+}
+
+func (w *PieceWeights) of(pieceType PieceType) int {
+	switch pieceType {
+	case Pawn:
+		return w.Pawn
+	case Knight:
+		return w.Knight
+	case Bishop:
+		return w.Bishop
+	case Rook:
+		return w.Rook
+	case Queen:
+		return w.Queen
+	case King:
+		return w.King
+	}
+	return 0
+}
+
+// PieceScores holds a midgame and endgame value per piece type.
+type PieceScores struct {
+	Knight scorePair `json:"knight"`
+	Bishop scorePair `json:"bishop"`
+	Rook   scorePair `json:"rook"`
+	Queen  scorePair `json:"queen"`
+}
+
+func (s *PieceScores) of(pieceType PieceType) scorePair {
+	switch pieceType {
+	case Knight:
+		return s.Knight
+	case Bishop:
+		return s.Bishop
+	case Rook:
+		return s.Rook
+	case Queen:
+		return s.Queen
+	}
+	return scorePair{}
+}
+
+// PieceTables is the midgame and endgame piece-square table of a piece type, written
+// from white's side with a8 first.
+type PieceTables struct {
+	Mg [64]int `json:"mg"`
+	Eg [64]int `json:"eg"`
+}
+
+// PieceSquareTables holds the piece-square tables of every piece type.
+type PieceSquareTables struct {
+	Pawn   PieceTables `json:"pawn"`
+	Knight PieceTables `json:"knight"`
+	Bishop PieceTables `json:"bishop"`
+	Rook   PieceTables `json:"rook"`
+	Queen  PieceTables `json:"queen"`
+	King   PieceTables `json:"king"`
+}
+
+func (t *PieceSquareTables) of(pieceType PieceType) *PieceTables {
+	switch pieceType {
+	case Pawn:
+		return &t.Pawn
+	case Knight:
+		return &t.Knight
+	case Bishop:
+		return &t.Bishop
+	case Rook:
+		return &t.Rook
+	case Queen:
+		return &t.Queen
+	}
+	return &t.King
+}
+
+// MarshalJSON writes a pair as [midgame, endgame].
+func (s scorePair) MarshalJSON() ([]byte, error) {
+	return json.Marshal([2]int{s.mg, s.eg})
+}
+
+// UnmarshalJSON reads a pair written as [midgame, endgame].
+func (s *scorePair) UnmarshalJSON(data []byte) error {
+	var pair [2]int
+	if err := json.Unmarshal(data, &pair); err != nil {
+		return err
+	}
+	s.mg, s.eg = pair[0], pair[1]
+	return nil
+}
+
+// DefaultEvalParams returns the weights the engine is built with.
+func DefaultEvalParams() EvalParams {
+	return EvalParams{
+		PieceValues: PieceWeights{Pawn: 100, Knight: 320, Bishop: 330, Rook: 500, Queen: 900, King: 20000},
+		PieceSquareTables: PieceSquareTables{
+			Pawn:   PieceTables{pawn_pos_weight, pawn_pos_weight_eg},
+			Knight: PieceTables{knight_pos_weight, knight_pos_weight_eg},
+			Bishop: PieceTables{bishop_pos_weight, bishop_pos_weight_eg},
+			Rook:   PieceTables{rook_pos_weight, rook_pos_weight_eg},
+			Queen:  PieceTables{queen_pos_weight, queen_pos_weight_eg},
+			King:   PieceTables{king_pos_weight, king_pos_weight_eg},
+		},
+
+		DoubledPawn:  scorePair{-10, -20},
+		IsolatedPawn: scorePair{-10, -15},
+		BackwardPawn: scorePair{-8, -10},
+		PassedPawn:   [8]scorePair{{0, 0}, {5, 10}, {10, 20}, {15, 35}, {25, 60}, {40, 100}, {60, 150}, {0, 0}},
+
+		KingAttackWeight:     PieceWeights{Knight: 2, Bishop: 2, Rook: 3, Queen: 5},
+		MaxKingDanger:        500,
+		PawnShield:           [2]int{12, 6},
+		MissingShield:        -15,
+		PawnStorm:            [4]int{0, -25, -15, -5},
+		SemiOpenFileNearKing: -15,
+		OpenFileNearKing:     -25,
+
+		Mobility:         PieceScores{Knight: scorePair{4, 4}, Bishop: scorePair{5, 5}, Rook: scorePair{2, 4}, Queen: scorePair{1, 2}},
+		MobilityAverage:  PieceWeights{Knight: 4, Bishop: 6, Rook: 7, Queen: 13},
+		BishopPair:       scorePair{30, 50},
+		RookOpenFile:     scorePair{25, 10},
+		RookSemiOpenFile: scorePair{12, 6},
+		RookOnSeventh:    scorePair{20, 30},
+		KnightOutpost:    scorePair{20, 10},
+		BishopOutpost:    scorePair{10, 5},
+		TrappedBishop:    scorePair{-100, -100},
+		TrappedRook:      scorePair{-40, 0},
+	}
+}
+
+// evalParams are the weights in use by the evaluation.
+var evalParams = DefaultEvalParams()
+
+// evalParamsVersion changes whenever evalParams do, so sums worked out with the old
+// weights can be told apart.
+var evalParamsVersion uint64 = 1
+
+// SetEvalParams makes the evaluation use the given weights.
+func SetEvalParams(params EvalParams) {
+	evalParams = params
+	evalParamsVersion++
+	// cached pawn scores were computed with the old weights
+	pawnTable = [len(pawnTable)]pawnEntry{}
+}
+
+// LoadEvalParams reads weights from a JSON file. Weights missing from the file keep
+// their default values, so a file only needs to list the ones it changes.
+func LoadEvalParams(path string) (EvalParams, error) {
+	params := DefaultEvalParams()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return params, err
+	}
+	if err := json.Unmarshal(data, &params); err != nil {
+		return params, fmt.Errorf("reading eval params %s: %w", path, err)
+	}
+	return params, nil
+}
+
+// Save writes the weights to a JSON file that LoadEvalParams can read back.
+func (p EvalParams) Save(path string) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
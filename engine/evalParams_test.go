@@ -0,0 +1,62 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestEvalParamsRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "params.json")
+	params := DefaultEvalParams()
+	params.BishopPair = scorePair{41, 57}
+	params.PassedPawn[5] = scorePair{33, 99}
+	params.PieceSquareTables.Knight.Eg[12] = -7
+	if err := params.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadEvalParams(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, params) {
+		t.Error("weights read back differ from the weights saved")
+	}
+}
+
+func TestLoadEvalParamsKeepsDefaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "params.json")
+	if err := os.WriteFile(path, []byte(`{"bishopPair": [1, 2]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadEvalParams(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := DefaultEvalParams()
+	want.BishopPair = scorePair{1, 2}
+	if !reflect.DeepEqual(loaded, want) {
+		t.Error("weights missing from the file did not keep their defaults")
+	}
+
+	if err := os.WriteFile(path, []byte(`{"bishopPair": 3}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadEvalParams(path); err == nil {
+		t.Error("malformed weights were accepted")
+	}
+}
+
+func TestSetEvalParams(t *testing.T) {
+	defer SetEvalParams(DefaultEvalParams())
+	g := parseGame("4k3/8/8/8/8/8/8/2B1KB2 w - - 0 1")
+	before := g.board.eval(true)
+
+	params := DefaultEvalParams()
+	params.BishopPair = params.BishopPair.add(scorePair{100, 100})
+	SetEvalParams(params)
+	if got := g.board.eval(true); got != before+100 {
+		t.Errorf("eval %d after raising the bishop pair by 100, want %d", got, before+100)
+	}
+}
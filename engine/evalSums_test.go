@@ -0,0 +1,41 @@
+package engine
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestEvalSumsFollowMoves(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 10; i++ {
+		randomPlayouts(t, r, boardTestFens(), 100, func(context string, b *Board, whiteToMove bool) {
+			if b.sums.version != evalParamsVersion {
+				b.sums = b.computeEvalSums()
+			}
+			if want := b.computeEvalSums(); b.sums != want {
+				t.Fatalf("%s: sums %+v, from scratch %+v", context, b.sums, want)
+			}
+			scratch := *b
+			scratch.sums = evalSums{}
+			if got, want := b.eval(whiteToMove), scratch.eval(whiteToMove); got != want {
+				t.Fatalf("%s: eval %d with kept sums, %d from scratch", context, got, want)
+			}
+		})
+	}
+}
+
+func TestEvalSumsFollowParams(t *testing.T) {
+	defer SetEvalParams(DefaultEvalParams())
+	b := testBoard(evalTestFens[1])
+	b.eval(true)
+
+	params := DefaultEvalParams()
+	params.PieceValues.Knight += 50
+	params.PieceSquareTables.Pawn.Mg[20] += 30
+	SetEvalParams(params)
+	scratch := *b
+	scratch.sums = evalSums{}
+	if got, want := b.eval(true), scratch.eval(true); got != want {
+		t.Errorf("eval %d with sums kept from the old weights, %d from scratch", got, want)
+	}
+}
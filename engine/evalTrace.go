@@ -0,0 +1,93 @@
+package engine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// StageScore is a score split into its midgame and endgame parts, in centipawns.
+type StageScore struct {
+	Mg int `json:"mg"`
+	Eg int `json:"eg"`
+}
+
+// EvalTerm is one term of the evaluation, scored separately for each side.
+type EvalTerm struct {
+	Name  string     `json:"name"`
+	White StageScore `json:"white"`
+	Black StageScore `json:"black"`
+	Total StageScore `json:"total"` // white minus black
+}
+
+// EvalTrace breaks the static evaluation of a position down into its terms.
+// All scores are from white's point of view.
+type EvalTrace struct {
+	Terms    []EvalTerm `json:"terms"`
+	Total    StageScore `json:"total"`
+	Phase    int        `json:"phase"`             // totalPhase in the opening down to 0 with only pawns left
+	MaxPhase int        `json:"maxPhase"`          // the phase with all pieces on the board
+	Final    int        `json:"final"`             // total blended by phase, as used by the search
+	Endgame  string     `json:"endgame,omitempty"` // recognised endgame that replaces the terms in Final
+}
+
+// evalTrace evaluates the board term by term.
+func (b *Board) evalTrace(whiteToMove bool) EvalTrace {
+	trace := EvalTrace{Phase: b.gamePhase(), MaxPhase: totalPhase}
+	var total scorePair
+	for i, term := range b.evalTerms() {
+		diff := term[0].sub(term[1])
+		total = total.add(diff)
+		trace.Terms = append(trace.Terms, EvalTerm{
+			Name:  evalTermNames[i],
+			White: StageScore{term[0].mg, term[0].eg},
+			Black: StageScore{term[1].mg, term[1].eg},
+			Total: StageScore{diff.mg, diff.eg},
+		})
+	}
+	trace.Total = StageScore{total.mg, total.eg}
+	trace.Final = int(taper(total.mg, total.eg, trace.Phase))
+	if score, ok := b.evalEndgame(whiteToMove); ok {
+		trace.Endgame = b.endgameName()
+		trace.Final = int(score)
+	}
+	return trace
+}
+
+// EvalTraceJSON evaluates the position given as a fen string and returns the
+// breakdown of the evaluation as JSON.
+func EvalTraceJSON(fen string) ([]byte, error) {
+	g := parseGame(fen)
+	if g.board.pieces[whiteIndex][kingIndex] == 0 || g.board.pieces[blackIndex][kingIndex] == 0 {
+		return nil, errors.New("invalid fen: both sides need a king")
+	}
+	return json.Marshal(g.board.evalTrace(g.whiteToMove))
+}
+
+// String lays the trace out as a table, one row per term.
+func (t EvalTrace) String() string {
+	var sb strings.Builder
+	row := func(name, white, black, total string) {
+		sb.WriteString(fmt.Sprintf("%15s | %11s | %11s | %11s\n", name, white, black, total))
+	}
+	cell := func(s StageScore) string {
+		return fmt.Sprintf("%5d %5d", s.Mg, s.Eg)
+	}
+	separator := strings.Repeat("-", 16) + "+" + strings.Repeat("-", 13) + "+" + strings.Repeat("-", 13) + "+" + strings.Repeat("-", 12) + "\n"
+
+	row("Term", "White  ", "Black  ", "Total  ")
+	row("", "MG    EG", "MG    EG", "MG    EG")
+	sb.WriteString(separator)
+	for _, term := range t.Terms {
+		row(term.Name, cell(term.White), cell(term.Black), cell(term.Total))
+	}
+	sb.WriteString(separator)
+	row("Total", "", "", cell(t.Total))
+	sb.WriteString(fmt.Sprintf("\nPhase: %d/%d\n", t.Phase, t.MaxPhase))
+	if t.Endgame != "" {
+		sb.WriteString(fmt.Sprintf("Endgame: %s\n", t.Endgame))
+	}
+	sb.WriteString(fmt.Sprintf("Final evaluation: %+.2f (white side)\n", float64(t.Final)/100))
+	return sb.String()
+}
@@ -0,0 +1,57 @@
+package engine
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestEvalTraceAddsUpToEval(t *testing.T) {
+	for _, fen := range evalTestFens {
+		g := parseGame(fen)
+		trace := g.board.evalTrace(g.whiteToMove)
+		var mg, eg int
+		for _, term := range trace.Terms {
+			if term.Total.Mg != term.White.Mg-term.Black.Mg || term.Total.Eg != term.White.Eg-term.Black.Eg {
+				t.Errorf("%s: term %s total %v is not white %v minus black %v", fen, term.Name, term.Total, term.White, term.Black)
+			}
+			mg, eg = mg+term.Total.Mg, eg+term.Total.Eg
+		}
+		if trace.Total != (StageScore{mg, eg}) {
+			t.Errorf("%s: trace total %v, terms add up to %v", fen, trace.Total, StageScore{mg, eg})
+		}
+		if want := int(g.board.eval(g.whiteToMove)); trace.Final != want {
+			t.Errorf("%s: trace final %d, eval %d", fen, trace.Final, want)
+		}
+	}
+}
+
+func TestEvalTraceJSON(t *testing.T) {
+	data, err := EvalTraceJSON(startPosition)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var trace EvalTrace
+	if err := json.Unmarshal(data, &trace); err != nil {
+		t.Fatal(err)
+	}
+	if len(trace.Terms) != len(evalTermNames) || trace.Phase != totalPhase || trace.MaxPhase != totalPhase {
+		t.Errorf("start position trace has %d terms and phase %d/%d", len(trace.Terms), trace.Phase, trace.MaxPhase)
+	}
+	if trace.Final != 0 {
+		t.Errorf("start position evaluates to %d, want 0", trace.Final)
+	}
+	if _, err := EvalTraceJSON("8/8/8/8/8/8/8/4K3 w - - 0 1"); err == nil {
+		t.Error("fen without a black king was accepted")
+	}
+}
+
+func TestEvalTraceNamesEndgame(t *testing.T) {
+	trace := testBoard("8/8/8/8/8/8/8/KR5k w - - 0 1").evalTrace(true)
+	if trace.Endgame == "" {
+		t.Error("KRvK not recognised as an endgame")
+	}
+	if !strings.Contains(trace.String(), "Endgame: "+trace.Endgame) {
+		t.Errorf("table does not name the endgame:\n%s", trace)
+	}
+}
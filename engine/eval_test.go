@@ -0,0 +1,94 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+	"unicode"
+)
+
+// evalTestFens are positions from every stage of the game.
+var evalTestFens = []string{
+	"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+	"r1bqkb1r/pppp1ppp/2n2n2/4p3/2B1P3/5N2/PPPP1PPP/RNBQK2R w KQkq - 4 4",
+	"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+	"r4rk1/1pp1qppp/p1np1n2/2b1p1B1/2B1P1b1/P1NP1N2/1PP1QPPP/R4RK1 w - - 0 10",
+	"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+	"8/5pk1/6p1/8/3P4/6P1/5PK1/8 b - - 0 40",
+}
+
+// mirrorFen swaps the colours of a position: the board is flipped top to bottom, the
+// pieces change colour and so does the side to move.
+func mirrorFen(fen string) string {
+	fields := strings.Fields(fen)
+	ranks := strings.Split(fields[0], "/")
+	for i, j := 0, len(ranks)-1; i < j; i, j = i+1, j-1 {
+		ranks[i], ranks[j] = ranks[j], ranks[i]
+	}
+	swap := func(r rune) rune {
+		if unicode.IsUpper(r) {
+			return unicode.ToLower(r)
+		}
+		return unicode.ToUpper(r)
+	}
+	fields[0] = strings.Map(swap, strings.Join(ranks, "/"))
+	if fields[1] == "w" {
+		fields[1] = "b"
+	} else {
+		fields[1] = "w"
+	}
+	if fields[2] != "-" {
+		fields[2] = strings.Map(swap, fields[2])
+	}
+	if fields[3] != "-" {
+		fields[3] = fields[3][:1] + string("87654321"[fields[3][1]-'1'])
+	}
+	return strings.Join(fields, " ")
+}
+
+// testBoard sets up the board of a fen string.
+func testBoard(fen string) *Board {
+	g := parseGame(fen)
+	return &g.board
+}
+
+func TestEvalIsSymmetric(t *testing.T) {
+	for _, fen := range evalTestFens {
+		g, m := parseGame(fen), parseGame(mirrorFen(fen))
+		if got, want := m.board.eval(m.whiteToMove), -g.board.eval(g.whiteToMove); got != want {
+			t.Errorf("eval of %s mirrored = %d, want %d", fen, got, want)
+		}
+	}
+}
+
+func TestGamePhase(t *testing.T) {
+	if phase := testBoard(evalTestFens[0]).gamePhase(); phase != totalPhase {
+		t.Errorf("start position phase %d, want %d", phase, totalPhase)
+	}
+	if phase := testBoard("8/5pk1/6p1/8/3P4/6P1/5PK1/8 b - - 0 40").gamePhase(); phase != 0 {
+		t.Errorf("pawn ending phase %d, want 0", phase)
+	}
+	// extra queens do not push the phase past the start
+	if phase := testBoard("QQQQkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQk - 0 1").gamePhase(); phase != totalPhase {
+		t.Errorf("phase with promoted queens %d, want %d", phase, totalPhase)
+	}
+}
+
+func TestTaper(t *testing.T) {
+	if got := taper(100, -50, totalPhase); got != 100 {
+		t.Errorf("taper at full phase = %d, want the midgame value", got)
+	}
+	if got := taper(100, -50, 0); got != -50 {
+		t.Errorf("taper at phase 0 = %d, want the endgame value", got)
+	}
+	if got := taper(100, -50, totalPhase/2); got != 25 {
+		t.Errorf("taper halfway = %d, want 25", got)
+	}
+}
+
+func TestKingCentralisesInEndgame(t *testing.T) {
+	centre := testBoard("8/p7/8/3k4/8/3K4/P7/8 w - - 0 1")
+	corner := testBoard("8/p7/8/3k4/8/8/P7/K7 w - - 0 1")
+	if centre.eval(true) <= corner.eval(true) {
+		t.Errorf("king in the centre %d, not better than in the corner %d", centre.eval(true), corner.eval(true))
+	}
+}
@@ -0,0 +1,28 @@
+package engine
+
+// Evaluator scores positions for the search.
+type Evaluator interface {
+	// Evaluate returns the score of the board in centipawns from white's point of view.
+	Evaluate(b *Board, whiteToMove bool) Score
+	// Name describes the evaluator for info strings.
+	Name() string
+}
+
+// HandCraftedEval is the built-in evaluation, weighted by the current EvalParams.
+type HandCraftedEval struct{}
+
+func (HandCraftedEval) Evaluate(b *Board, whiteToMove bool) Score {
+	return b.eval(whiteToMove)
+}
+
+func (HandCraftedEval) Name() string {
+	return "hand-crafted eval"
+}
+
+// evaluator is the evaluation used by the search.
+var evaluator Evaluator = HandCraftedEval{}
+
+// SetEvaluator makes the search use the given evaluation.
+func SetEvaluator(e Evaluator) {
+	evaluator = e
+}
@@ -0,0 +1,266 @@
+package engine
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Game is a board together with the state that spans more than one position:
+// whose turn it is, every position reached so far and the move counters.
+type Game struct {
+	board          Board
+	whiteToMove    bool
+	history        []uint64 // position keys of every position reached, oldest first
+	halfmoveClock  int      // plies since the last capture or pawn move
+	fullmoveNumber int      // starts at 1 and goes up after each move of black
+
+	undoStack []gameUndo // moves played, last one last, with what it takes to take them back
+	redoStack []gameUndo // moves taken back, the last one taken back last
+
+	// set when the game ends off the board, by resignation or timeout
+	status GameStatus
+	reason ResultReason
+}
+
+var mainGame Game
+
+// startPosition is the fen string of the standard starting position.
+const startPosition = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+
+// newGame starts a game from the given board. Castling is allowed wherever the king
+// and rook still stand on their starting squares.
+func newGame(b Board, whiteToMove bool) Game {
+	for _, c := range castlingSquares {
+		if b.pieceBitboard(King, c.isWhite)&c.king != 0 && b.pieceBitboard(Rook, c.isWhite)&c.rook != 0 {
+			b.castling |= c.right
+		}
+	}
+	g := Game{board: b, whiteToMove: whiteToMove, fullmoveNumber: 1}
+	g.history = append(g.history, b.positionKey(whiteToMove))
+	return g
+}
+
+// parseGame starts a game from a fen string, reading the side to move, the castling
+// and en passant rights and the move counters when they are present.
+func parseGame(fen string) Game {
+	fields := strings.Fields(fen)
+	g := newGame(parse(fen), len(fields) < 2 || fields[1] != "b")
+	b := &g.board
+	if len(fields) >= 3 {
+		b.castling = 0
+		for _, c := range castlingSquares {
+			if strings.IndexByte(fields[2], c.fen) >= 0 {
+				b.castling |= c.right
+			}
+		}
+	}
+	if len(fields) >= 4 && isSquare(fields[3]) {
+		b.epSquare = b.notationToPos(fields[3])
+	}
+	if len(fields) >= 5 {
+		if clock, err := strconv.Atoi(fields[4]); err == nil {
+			g.halfmoveClock = clock
+		}
+	}
+	if len(fields) >= 6 {
+		if number, err := strconv.Atoi(fields[5]); err == nil && number > 0 {
+			g.fullmoveNumber = number
+		}
+	}
+	g.history = append(g.history[:0], b.positionKey(g.whiteToMove))
+	return g
+}
+
+// parsePosition reads the arguments of a position command: a fen string or startpos,
+// either of them optionally after fen, followed by any moves played from there in UCI
+// notation. The game is returned as far as the moves could be played.
+func parsePosition(position string) (Game, error) {
+	setup, moves, _ := strings.Cut(position, "moves")
+	setup = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(setup), "fen "))
+	var g Game
+	if setup == "startpos" {
+		var b Board
+		b.Initialize()
+		g = newGame(b, true)
+	} else {
+		g = parseGame(setup)
+	}
+	for _, uci := range strings.Fields(moves) {
+		m, err := g.parseUCIMove(uci)
+		if err != nil {
+			return g, err
+		}
+		g.play(m)
+	}
+	return g, nil
+}
+
+// gameUndo is a move played in a game together with the state it overwrote.
+type gameUndo struct {
+	move          Move
+	undo          moveUndo
+	halfmoveClock int
+	hash          uint64
+
+	byEngine bool     // chosen by the engine rather than the player
+	notes    pgnNotes // what to say about the move in PGN
+}
+
+// play makes a move for the side to move on the game board and records the
+// resulting position. Moves taken back can no longer be redone.
+func (g *Game) play(m Move) {
+	isWhite := g.whiteToMove
+	pieceType := g.board.getPieceType(m.from())
+	u := gameUndo{move: m, halfmoveClock: g.halfmoveClock, hash: g.board.hash}
+	u.undo = g.board.doMove(m, isWhite)
+	g.undoStack = append(g.undoStack, u)
+	g.redoStack = nil
+	g.recordMove(isWhite, nextHalfmoveClock(g.halfmoveClock, pieceType, m.isCapture()))
+}
+
+// Undo takes back the last move played and returns it, or reports false at the
+// start of the game. A game that ended by resignation or timeout goes on again from
+// the position before.
+func (g *Game) Undo() (Move, bool) {
+	n := len(g.undoStack)
+	if n == 0 {
+		return noMove, false
+	}
+	u := g.undoStack[n-1]
+	g.undoStack = g.undoStack[:n-1]
+	g.whiteToMove = !g.whiteToMove
+	if !g.whiteToMove {
+		g.fullmoveNumber--
+	}
+	g.board.undoMove(u.move, g.whiteToMove, u.undo)
+	g.board.hash = u.hash
+	g.halfmoveClock = u.halfmoveClock
+	g.history = g.history[:len(g.history)-1]
+	g.redoStack = append(g.redoStack, u)
+	g.status, g.reason = Ongoing, NoReason
+	return u.move, true
+}
+
+// Redo plays again the last move taken back and returns it, or reports false when
+// no move was taken back since the last one played.
+func (g *Game) Redo() (Move, bool) {
+	n := len(g.redoStack)
+	if n == 0 {
+		return noMove, false
+	}
+	u, redo := g.redoStack[n-1], g.redoStack[:n-1]
+	g.play(u.move)
+	g.undoStack[len(g.undoStack)-1].byEngine = u.byEngine
+	g.undoStack[len(g.undoStack)-1].notes = u.notes
+	g.redoStack = redo
+	return u.move, true
+}
+
+// rewind takes back every move played, leaving them to be redone.
+func (g *Game) rewind() {
+	for {
+		if _, ok := g.Undo(); !ok {
+			return
+		}
+	}
+}
+
+// lastMove returns the last move played, or noMove at the start of the game.
+func (g *Game) lastMove() Move {
+	if n := len(g.undoStack); n > 0 {
+		return g.undoStack[n-1].move
+	}
+	return noMove
+}
+
+// fen writes the current position as a fen string.
+func (g *Game) fen() string {
+	b := &g.board
+	var sb strings.Builder
+	for rank := 7; rank >= 0; rank-- {
+		empty := 0
+		for file := 0; file < 8; file++ {
+			pos := uint64(1) << (rank*8 + 7 - file)
+			pieceType := b.getPieceType(pos)
+			if pieceType == NoPiece {
+				empty++
+				continue
+			}
+			if empty > 0 {
+				sb.WriteString(strconv.Itoa(empty))
+				empty = 0
+			}
+			if pos&b.colours[whiteIndex] != 0 {
+				sb.WriteRune(rune(pieceType))
+			} else {
+				sb.WriteRune(unicode.ToLower(rune(pieceType)))
+			}
+		}
+		if empty > 0 {
+			sb.WriteString(strconv.Itoa(empty))
+		}
+		if rank > 0 {
+			sb.WriteByte('/')
+		}
+	}
+	if g.whiteToMove {
+		sb.WriteString(" w ")
+	} else {
+		sb.WriteString(" b ")
+	}
+	castling := ""
+	for _, c := range castlingSquares {
+		if b.castling&c.right != 0 {
+			castling += string(c.fen)
+		}
+	}
+	if castling == "" {
+		castling = "-"
+	}
+	ep := "-"
+	if b.epSquare != 0 {
+		ep = uciSquare(b.epSquare)
+	}
+	fmt.Fprintf(&sb, "%s %s %d %d", castling, ep, g.halfmoveClock, g.fullmoveNumber)
+	return sb.String()
+}
+
+// polyglotKey returns the Polyglot key of the current position.
+func (g *Game) polyglotKey() uint64 {
+	return g.board.polyglotKey(g.whiteToMove)
+}
+
+// recordMove hands the turn over after isWhite moved and records the new position.
+func (g *Game) recordMove(isWhite bool, halfmoveClock int) {
+	g.halfmoveClock = halfmoveClock
+	g.whiteToMove = !isWhite
+	if !isWhite {
+		g.fullmoveNumber++
+	}
+	g.history = append(g.history, g.board.positionKey(g.whiteToMove))
+}
+
+// repetitions returns how many times the current position occurred before.
+// Only positions since the last capture or pawn move can repeat.
+func (g *Game) repetitions() int {
+	last := len(g.history) - 1
+	count := 0
+	for i := last - 2; i >= 0 && i >= last-g.halfmoveClock; i -= 2 {
+		if g.history[i] == g.history[last] {
+			count++
+		}
+	}
+	return count
+}
+
+// isThreefoldRepetition reports whether the current position has now occurred three times.
+func (g *Game) isThreefoldRepetition() bool {
+	return g.repetitions() >= 2
+}
+
+// isFiftyMoveDraw reports whether fifty moves by each side passed without a capture or pawn move.
+func (g *Game) isFiftyMoveDraw() bool {
+	return g.halfmoveClock >= 100
+}
@@ -0,0 +1,134 @@
+package engine
+
+import "testing"
+
+// newTestGame sets up a game from the arguments of a position command.
+func newTestGame(t *testing.T, position string) Game {
+	t.Helper()
+	g, err := parsePosition(position)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestThreefoldRepetition(t *testing.T) {
+	shuffle := "g1f3 g8f6 f3g1 f6g8 "
+	g := newTestGame(t, "startpos moves "+shuffle+"g1f3 g8f6 f3g1")
+	if g.isThreefoldRepetition() {
+		t.Fatal("threefold repetition before the position occurred three times")
+	}
+	m, err := g.parseUCIMove("f6g8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.play(m)
+	if !g.isThreefoldRepetition() {
+		t.Fatal("start position reached three times is not a repetition")
+	}
+	if status, reason := g.Result(); status != Draw || reason != Repetition {
+		t.Errorf("Result() = %v, %v, want a draw by repetition", status, reason)
+	}
+}
+
+func TestRepetitionNeedsSameRights(t *testing.T) {
+	// the king walks away and back, losing the castling rights, so the first position
+	// differs from the later ones
+	g := newTestGame(t, "fen r3k3/8/8/8/8/8/8/4K2R w K - 0 1 moves e1f1 a8b8 f1e1 b8a8 e1f1 a8b8 f1e1 b8a8")
+	if g.repetitions() != 1 {
+		t.Errorf("repetitions() = %d, want 1 as castling was lost after the first position", g.repetitions())
+	}
+}
+
+func TestFiftyMoveRule(t *testing.T) {
+	g := newTestGame(t, "fen 4k3/8/8/8/8/8/4P3/4K2R w - - 98 80 moves h1h2")
+	if g.isFiftyMoveDraw() {
+		t.Fatal("fifty-move draw after 99 plies")
+	}
+	m, _ := g.parseUCIMove("e8d8")
+	g.play(m)
+	if !g.isFiftyMoveDraw() {
+		t.Fatal("no fifty-move draw after 100 plies")
+	}
+	if status, reason := g.Result(); status != Draw || reason != FiftyMove {
+		t.Errorf("Result() = %v, %v, want a draw by the fifty-move rule", status, reason)
+	}
+
+	g = newTestGame(t, "fen 4k3/8/8/8/8/8/4P3/4K2R w - - 98 80 moves e2e4")
+	if g.halfmoveClock != 0 {
+		t.Errorf("halfmove clock %d after a pawn move, want 0", g.halfmoveClock)
+	}
+}
+
+func TestSearchSeesRepetition(t *testing.T) {
+	g := newTestGame(t, "startpos moves g1f3 g8f6 f3g1")
+	startSearch(g.history)
+	m, _ := g.parseUCIMove("f6g8")
+	g.board.doMove(m, false)
+	g.board.pushSearchPosition(true)
+	if !isSearchDraw(g.halfmoveClock + 1) {
+		t.Error("a position repeated inside the search is not a draw")
+	}
+	popSearchPosition()
+	if isSearchDraw(g.halfmoveClock) {
+		t.Error("the root position is a draw")
+	}
+}
+
+func TestUndoRedo(t *testing.T) {
+	g := newTestGame(t, "startpos moves e2e4 e7e5 g1f3")
+	var fens []string
+	var histories []int
+	for {
+		fens = append(fens, g.fen())
+		histories = append(histories, len(g.history))
+		if _, ok := g.Undo(); !ok {
+			break
+		}
+	}
+	if got := g.fen(); got != startPosition {
+		t.Fatalf("position %s after taking every move back", got)
+	}
+	for i := len(fens) - 2; i >= 0; i-- {
+		if _, ok := g.Redo(); !ok {
+			t.Fatal("could not redo a move taken back")
+		}
+		if got := g.fen(); got != fens[i] || len(g.history) != histories[i] {
+			t.Errorf("position %s with %d keys after redo, want %s with %d", got, len(g.history), fens[i], histories[i])
+		}
+	}
+	if _, ok := g.Redo(); ok {
+		t.Error("redo with nothing taken back")
+	}
+	if hash, _ := g.board.computeHash(); g.board.hash != hash {
+		t.Error("hash out of step after undo and redo")
+	}
+
+	g.Undo()
+	g.Undo()
+	m, _ := g.parseUCIMove("c7c5")
+	g.play(m)
+	if _, ok := g.Redo(); ok {
+		t.Error("redo after a new move was played")
+	}
+	if m, _ := g.Undo(); m.String() != "c7c5" {
+		t.Errorf("undo took back %v, want c7c5", m)
+	}
+}
+
+func TestUndoResumesEndedGame(t *testing.T) {
+	for _, end := range []func(g *Game){
+		func(g *Game) { g.Resign(true) },
+		func(g *Game) { g.Timeout(false) },
+	} {
+		g := newTestGame(t, "startpos moves e2e4 e7e5")
+		end(&g)
+		if status, _ := g.Result(); status == Ongoing {
+			t.Fatal("game not over after resigning or running out of time")
+		}
+		g.Undo()
+		if status, reason := g.Result(); status != Ongoing || reason != NoReason {
+			t.Errorf("after undo Result() = %v, %v, want the game going on", status, reason)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package engine
+
+import "math/bits"
+
+// kingZone returns the squares around the king together with the rank in front of those,
+// which are the squares an attack on the king has to go through.
+func kingZone(king uint64, isWhite bool) uint64 {
+	zone := king | kingAttacks(king)
+	if isWhite {
+		zone |= zone << 8
+	} else {
+		zone |= zone >> 8
+	}
+	return zone
+}
+
+// evalKingSafety scores the safety of each king. It only matters while there are pieces
+// left to attack with, so the scores are midgame only.
+func (b *Board) evalKingSafety() (scorePair, scorePair) {
+	white := scorePair{b.kingSafety(true), 0}
+	black := scorePair{b.kingSafety(false), 0}
+	return white, black
+}
+
+// kingSafety scores the safety of the given side's king: the enemy pieces attacking the
+// squares around it, its pawn shield, enemy pawn storms and open files in front of it.
+func (b *Board) kingSafety(isWhite bool) int {
+	own, enemy := &b.pieces[colourIndex(isWhite)], &b.pieces[colourIndex(!isWhite)]
+	king, ownPawns, enemyPawns := own[kingIndex], own[pawnIndex], enemy[pawnIndex]
+	enemyKnights, enemyBishops, enemyRooks, enemyQueens := enemy[knightIndex], enemy[bishopIndex], enemy[rookIndex], enemy[queenIndex]
+	if king == 0 {
+		return 0
+	}
+	score := 0
+	sq := bits.TrailingZeros64(king)
+	kingFile := squareFile(sq)
+	zone := kingZone(king, isWhite)
+
+	// attackers of the king zone, weighted by piece type and by how many squares they hit
+	danger, attackers := 0, 0
+	countAttacks := func(pieces uint64, pieceType PieceType) {
+		for ; pieces != 0; pieces &= pieces - 1 {
+			pos := pieces & -pieces
+			var attacks uint64
+			switch pieceType {
+			case Knight:
+				attacks = knightAttacks(pos)
+			case Bishop:
+				attacks = slidingAttacks(pos, b.allPieces, diagDirs)
+			case Rook:
+				attacks = slidingAttacks(pos, b.allPieces, straightDirs)
+			case Queen:
+				attacks = slidingAttacks(pos, b.allPieces, allDirs)
+			}
+			if hits := bits.OnesCount64(attacks & zone); hits > 0 {
+				attackers++
+				danger += hits * evalParams.KingAttackWeight.of(pieceType)
+			}
+		}
+	}
+	countAttacks(enemyKnights, Knight)
+	countAttacks(enemyBishops, Bishop)
+	countAttacks(enemyRooks, Rook)
+	countAttacks(enemyQueens, Queen)
+	if attackers >= 2 {
+		score -= min(danger*danger/4, evalParams.MaxKingDanger)
+	}
+
+	// pawn shield, pawn storm and open files on the king's file and the ones next to it
+	for f := max(kingFile-1, 0); f <= min(kingFile+1, 7); f++ {
+		inFront := passedPawnMasks[colourIndex(isWhite)][sq] & fileMasks[f]
+
+		if relativeRank(sq, isWhite) <= 1 {
+			shield := ownPawns & inFront
+			if shield == 0 {
+				score += evalParams.MissingShield
+			} else {
+				distance := pawnDistance(shield, sq, isWhite)
+				if distance-1 < len(evalParams.PawnShield) {
+					score += evalParams.PawnShield[distance-1]
+				}
+			}
+		}
+
+		if storm := enemyPawns & inFront; storm != 0 {
+			if distance := pawnDistance(storm, sq, isWhite); distance < len(evalParams.PawnStorm) {
+				score += evalParams.PawnStorm[distance]
+			}
+		}
+
+		if ownPawns&fileMasks[f] == 0 {
+			if enemyPawns&fileMasks[f] == 0 {
+				score += evalParams.OpenFileNearKing
+			} else {
+				score += evalParams.SemiOpenFileNearKing
+			}
+		}
+	}
+	return score
+}
+
+// pawnDistance returns how many ranks the closest of the given pawns stands in front of
+// the square sq, seen from the given side.
+func pawnDistance(pawns uint64, sq int, isWhite bool) int {
+	var closest int
+	if isWhite {
+		closest = bits.TrailingZeros64(pawns)
+	} else {
+		closest = 63 - bits.LeadingZeros64(pawns)
+	}
+	return relativeRank(closest, isWhite) - relativeRank(sq, isWhite)
+}
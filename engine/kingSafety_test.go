@@ -0,0 +1,36 @@
+package engine
+
+import "testing"
+
+func TestKingSafety(t *testing.T) {
+	tests := []struct {
+		name         string
+		safer, worse string
+	}{
+		{"pawn shield",
+			"r1bq1rk1/pppp1ppp/2n2n2/4p3/4P3/2N2N2/PPPP1PPP/R1BQ1RK1 w - - 0 1",
+			"r1bq1rk1/pppp1ppp/2n2n2/4p3/4P3/2N2NPP/PPPP1P2/R1BQ1RK1 w - - 0 1"},
+		{"attackers in the king zone",
+			"r1b2rk1/pppp1ppp/2n2n2/4p3/4P3/2N2N2/PPPP1PPP/R1BQ1RK1 w - - 0 1",
+			"r1b2rk1/pppp1ppp/2n5/4p3/4P1nq/2N2N2/PPPP1PPP/R1BQ1RK1 w - - 0 1"},
+		{"open file in front of the king",
+			"r1bq1rk1/pppp1ppp/2n2n2/4p3/4P3/2N2N2/PPPP1PPP/R1BQ1RK1 w - - 0 1",
+			"r1bq1rk1/pppp1ppp/2n2n2/4p3/4P3/2N2N2/PPPP1P1P/R1BQ1RK1 w - - 0 1"},
+		{"pawn storm",
+			"r1bq1rk1/pppp1ppp/2n2n2/4p3/4P3/2N2N2/PPPP1PPP/R1BQ1RK1 w - - 0 1",
+			"r1bq1rk1/pppp1p2/2n2n2/4p3/4P1pp/2N2N2/PPPP1PPP/R1BQ1RK1 w - - 0 1"},
+	}
+	for _, tt := range tests {
+		safer, worse := testBoard(tt.safer).kingSafety(true), testBoard(tt.worse).kingSafety(true)
+		if worse >= safer {
+			t.Errorf("%s: white king safety %d, not worse than %d", tt.name, worse, safer)
+		}
+	}
+}
+
+func TestKingSafetyIsMidgameOnly(t *testing.T) {
+	white, black := testBoard(evalTestFens[3]).evalKingSafety()
+	if white.eg != 0 || black.eg != 0 {
+		t.Errorf("king safety endgame scores %d %d, want 0", white.eg, black.eg)
+	}
+}
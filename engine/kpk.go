@@ -0,0 +1,26 @@
+package engine
+
+//go:generate go run kpkgen.go
+
+// The KPK bitbase records, for every position with white king and pawn against the
+// black king, whether white wins, as one bit per position. It is worked out by
+// retrograde analysis in kpkgen.go and compiled in as kpkBits in kpkTable.go.
+//
+// Squares in this file are numbered a1 = 0 to h8 = 63. Positions are stored with the
+// pawn on the a to d files; the others are mirrored.
+const kpkSize = 2 * 64 * 64 * 24 // side to move, black king, white king, pawn square
+
+// kpkIndex packs a position: the pawn is on files a-d and ranks 2-7.
+func kpkIndex(whiteToMove bool, blackKing, whiteKing, pawn int) int {
+	stm := 0
+	if !whiteToMove {
+		stm = 1
+	}
+	return stm | blackKing<<1 | whiteKing<<7 | (pawn&7)<<13 | (6-pawn>>3)<<15
+}
+
+// kpkProbe reports whether white wins a KPK position, with the pawn on files a to d.
+func kpkProbe(whiteToMove bool, whiteKing, pawn, blackKing int) bool {
+	idx := kpkIndex(whiteToMove, blackKing, whiteKing, pawn)
+	return kpkBits[idx/64]&(1<<(idx%64)) != 0
+}
@@ -0,0 +1,195 @@
+//go:build ignore
+
+// kpkgen works out the KPK bitbase by retrograde analysis and writes it to
+// kpkTable.go. Run it with go generate.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"log"
+	"math/bits"
+	"os"
+)
+
+// The same layout as in kpk.go: squares a1 = 0 to h8 = 63, the pawn on the a to d files.
+const kpkSize = 2 * 64 * 64 * 24 // side to move, black king, white king, pawn square
+
+const (
+	kpkInvalid uint8 = 0
+	kpkUnknown uint8 = 1
+	kpkDraw    uint8 = 2
+	kpkWin     uint8 = 4
+)
+
+func main() {
+	table := generateKPK()
+
+	var buf bytes.Buffer
+	fmt.Fprintln(&buf, "// Code generated by kpkgen.go; DO NOT EDIT.")
+	fmt.Fprintln(&buf)
+	fmt.Fprintln(&buf, "package engine")
+	fmt.Fprintln(&buf)
+	fmt.Fprintln(&buf, "// kpkBits has one bit per KPK position, set when white wins. See kpkIndex.")
+	fmt.Fprintln(&buf, "var kpkBits = [kpkSize / 64]uint64{")
+	for i, word := range table {
+		if i%4 == 0 {
+			fmt.Fprint(&buf, "\t")
+		}
+		fmt.Fprintf(&buf, "0x%016x,", word)
+		if i%4 == 3 {
+			fmt.Fprintln(&buf)
+		} else {
+			fmt.Fprint(&buf, " ")
+		}
+	}
+	fmt.Fprintln(&buf, "}")
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile("kpkTable.go", src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// generateKPK classifies every position: first the ones decided on the spot, then
+// repeatedly the ones whose result follows from the positions they lead to, until
+// nothing changes. Whatever is still unknown then is a draw.
+func generateKPK() [kpkSize / 64]uint64 {
+	db := make([]uint8, kpkSize)
+	for idx := range db {
+		db[idx] = kpkInitial(idx)
+	}
+	for changed := true; changed; {
+		changed = false
+		for idx := range db {
+			if db[idx] == kpkUnknown {
+				if r := kpkClassify(db, idx); r != kpkUnknown {
+					db[idx] = r
+					changed = true
+				}
+			}
+		}
+	}
+	var table [kpkSize / 64]uint64
+	for idx, r := range db {
+		if r == kpkWin {
+			table[idx/64] |= 1 << (idx % 64)
+		}
+	}
+	return table
+}
+
+func kpkIndex(whiteToMove bool, blackKing, whiteKing, pawn int) int {
+	stm := 0
+	if !whiteToMove {
+		stm = 1
+	}
+	return stm | blackKing<<1 | whiteKing<<7 | (pawn&7)<<13 | (6-pawn>>3)<<15
+}
+
+func kpkDecode(idx int) (whiteToMove bool, blackKing, whiteKing, pawn int) {
+	whiteToMove = idx&1 == 0
+	blackKing = idx >> 1 & 63
+	whiteKing = idx >> 7 & 63
+	pawn = (6-(idx>>15&7))*8 + (idx >> 13 & 3)
+	return
+}
+
+// squareDistance is the number of king moves between two squares.
+func squareDistance(a, b int) int {
+	return max(abs(a%8-b%8), abs(a/8-b/8))
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// kpkKingAttacks returns the squares next to sq, as a bitboard in a1 = 0 order.
+func kpkKingAttacks(sq int) uint64 {
+	var attacks uint64
+	for dr := -1; dr <= 1; dr++ {
+		for df := -1; df <= 1; df++ {
+			r, f := sq/8+dr, sq%8+df
+			if (dr != 0 || df != 0) && r >= 0 && r < 8 && f >= 0 && f < 8 {
+				attacks |= 1 << (r*8 + f)
+			}
+		}
+	}
+	return attacks
+}
+
+// kpkPawnAttacks returns the squares a white pawn on sq attacks, in a1 = 0 order.
+func kpkPawnAttacks(sq int) uint64 {
+	var attacks uint64
+	if sq%8 > 0 {
+		attacks |= 1 << (sq + 7)
+	}
+	if sq%8 < 7 {
+		attacks |= 1 << (sq + 9)
+	}
+	return attacks
+}
+
+func kpkInitial(idx int) uint8 {
+	whiteToMove, bk, wk, psq := kpkDecode(idx)
+	if squareDistance(wk, bk) <= 1 || wk == psq || bk == psq ||
+		(whiteToMove && kpkPawnAttacks(psq)&(1<<bk) != 0) {
+		return kpkInvalid
+	}
+	// white promotes safely
+	if whiteToMove && psq/8 == 6 && wk != psq+8 &&
+		(squareDistance(bk, psq+8) > 1 || squareDistance(wk, psq+8) == 1) {
+		return kpkWin
+	}
+	if !whiteToMove {
+		// stalemate, or the pawn falls
+		attacked := kpkKingAttacks(wk) | kpkPawnAttacks(psq)
+		if kpkKingAttacks(bk)&^attacked == 0 ||
+			(squareDistance(bk, psq) == 1 && squareDistance(wk, psq) > 1) {
+			return kpkDraw
+		}
+	}
+	return kpkUnknown
+}
+
+// kpkClassify works out a position from the positions it leads to. White wins if any
+// move wins; black draws if any move draws.
+func kpkClassify(db []uint8, idx int) uint8 {
+	whiteToMove, bk, wk, psq := kpkDecode(idx)
+	var r uint8
+	if whiteToMove {
+		for moves := kpkKingAttacks(wk); moves != 0; moves &= moves - 1 {
+			r |= db[kpkIndex(false, bk, bits.TrailingZeros64(moves), psq)]
+		}
+		if psq/8 < 6 {
+			r |= db[kpkIndex(false, bk, wk, psq+8)]
+		}
+		if psq/8 == 1 && psq+8 != wk && psq+8 != bk {
+			r |= db[kpkIndex(false, bk, wk, psq+16)]
+		}
+		switch {
+		case r&kpkWin != 0:
+			return kpkWin
+		case r&kpkUnknown != 0:
+			return kpkUnknown
+		}
+		return kpkDraw
+	}
+	for moves := kpkKingAttacks(bk); moves != 0; moves &= moves - 1 {
+		r |= db[kpkIndex(true, bits.TrailingZeros64(moves), wk, psq)]
+	}
+	switch {
+	case r&kpkDraw != 0:
+		return kpkDraw
+	case r&kpkUnknown != 0:
+		return kpkUnknown
+	}
+	return kpkWin
+}
@@ -0,0 +1,198 @@
+package engine
+
+import "math/bits"
+
+// pinSet lists the pieces pinned to their king, each with the squares it may still
+// move to: along the line between the king and the pinning piece, up to and including
+// that piece. A king is pinned along at most eight lines.
+type pinSet struct {
+	n      int
+	pieces [8]uint64
+	rays   [8]uint64
+}
+
+// ray returns the squares a piece may move to without exposing its king.
+func (p *pinSet) ray(pos uint64) uint64 {
+	for i := 0; i < p.n; i++ {
+		if p.pieces[i] == pos {
+			return p.rays[i]
+		}
+	}
+	return ^uint64(0)
+}
+
+// between returns the squares strictly between two squares on a line, or nothing
+// when they do not share a rank, file or diagonal.
+func between(a, b uint64) uint64 {
+	if rookAttacks(a, 0)&b != 0 {
+		return rookAttacks(a, b) & rookAttacks(b, a)
+	}
+	if bishopAttacks(a, 0)&b != 0 {
+		return bishopAttacks(a, b) & bishopAttacks(b, a)
+	}
+	return 0
+}
+
+// attackedSquares returns every square a side attacks, given the occupancy.
+func (b *Board) attackedSquares(byWhite bool, occupied uint64) uint64 {
+	attacks := pawnAttacks(b.pieceBitboard(Pawn, byWhite), byWhite)
+	attacks |= kingAttacks(b.pieceBitboard(King, byWhite))
+	for pieces := b.pieceBitboard(Knight, byWhite); pieces != 0; pieces &= pieces - 1 {
+		attacks |= knightAttacks(pieces & -pieces)
+	}
+	queens := b.pieceBitboard(Queen, byWhite)
+	for pieces := b.pieceBitboard(Bishop, byWhite) | queens; pieces != 0; pieces &= pieces - 1 {
+		attacks |= bishopAttacks(pieces&-pieces, occupied)
+	}
+	for pieces := b.pieceBitboard(Rook, byWhite) | queens; pieces != 0; pieces &= pieces - 1 {
+		attacks |= rookAttacks(pieces&-pieces, occupied)
+	}
+	return attacks
+}
+
+// pins finds the pieces of a side pinned to its king: an enemy slider looks at the
+// king through exactly one piece, and that piece is the side's own.
+func (b *Board) pins(king uint64, isWhite bool) pinSet {
+	var p pinSet
+	own, enemy := b.ownPieces(isWhite), b.ownPieces(!isWhite)
+	queens := b.pieceBitboard(Queen, !isWhite)
+	snipers := rookAttacks(king, enemy) & (b.pieceBitboard(Rook, !isWhite) | queens)
+	snipers |= bishopAttacks(king, enemy) & (b.pieceBitboard(Bishop, !isWhite) | queens)
+	for ; snipers != 0; snipers &= snipers - 1 {
+		sniper := snipers & -snipers
+		line := between(king, sniper)
+		blockers := line & b.allPieces
+		if blockers&own != 0 && blockers&(blockers-1) == 0 {
+			p.pieces[p.n], p.rays[p.n] = blockers, line|sniper
+			p.n++
+		}
+	}
+	return p
+}
+
+// pieceMoves returns the pseudo-legal moves of the piece on pos.
+func (b *Board) pieceMoves(pos uint64, isWhite bool) uint64 {
+	switch b.getPieceType(pos) {
+	case Pawn:
+		return b.getPawnMoves(pos, isWhite)
+	case Knight:
+		return b.getKnightMoves(pos, isWhite)
+	case Bishop:
+		return b.getBishopMoves(pos, isWhite)
+	case Rook:
+		return b.getRookMoves(pos, isWhite)
+	case Queen:
+		return b.getQueenMoves(pos, isWhite)
+	case King:
+		return b.getKingMoves(pos, isWhite)
+	}
+	return 0
+}
+
+// generateMoves adds the legal moves of a side to the list without trying them on the
+// board. The pieces giving check and the pieces pinned to the king are found once:
+// in double check only the king moves, in single check the other pieces must take
+// the checker or step in between, and pinned pieces stay on their line. The king
+// steps only to squares the enemy does not attack with the king taken off the board,
+// so it cannot retreat along the line of a slider's check. A pawn reaching the last
+// rank makes one move for each piece it can become.
+func (b *Board) generateMoves(isWhite bool, moves *MoveList) {
+	king := b.pieceBitboard(King, isWhite)
+	enemy := b.ownPieces(!isWhite)
+	checkers := b.attackersTo(king, b.allPieces) & enemy
+	doubleCheck := checkers&(checkers-1) != 0
+
+	target := ^uint64(0)
+	if checkers != 0 {
+		target = checkers | between(king, checkers)
+	}
+	var danger uint64
+	if king != 0 {
+		danger = b.attackedSquares(!isWhite, b.allPieces&^king)
+	}
+	pins := b.pins(king, isWhite)
+
+	for pieces := b.ownPieces(isWhite); pieces != 0; pieces &= pieces - 1 {
+		pos := pieces & -pieces
+		var dests uint64
+		switch {
+		case pos == king:
+			dests = b.getKingMoves(pos, isWhite) &^ danger
+		case doubleCheck:
+			continue
+		default:
+			dests = b.pieceMoves(pos, isWhite) & target & pins.ray(pos)
+		}
+		isPawn := b.pieceBitboard(Pawn, isWhite)&pos != 0
+		for ; dests != 0; dests &= dests - 1 {
+			to := dests & -dests
+			var flags Move
+			if to&enemy != 0 {
+				flags = moveCapture
+			}
+			if isPawn {
+				addPawnMove(pos, to, flags, moves)
+			} else {
+				moves.add(newMove(pos, to, NoPiece, flags))
+			}
+		}
+	}
+	if !doubleCheck {
+		b.generateEnPassant(isWhite, moves)
+	}
+	if checkers == 0 {
+		b.generateCastling(isWhite, moves)
+	}
+}
+
+// addPawnMove adds a pawn move, once for each piece when the pawn reaches the last rank.
+func addPawnMove(from, to uint64, flags Move, moves *MoveList) {
+	if to&(bottomEdge|topEdge) != 0 {
+		for _, promotion := range promotionPieces[1:] {
+			moves.add(newMove(from, to, promotion, flags))
+		}
+		return
+	}
+	if from<<16 == to || from>>16 == to {
+		flags |= moveDoublePush
+	}
+	moves.add(newMove(from, to, NoPiece, flags))
+}
+
+// generateEnPassant adds the en passant captures of a side. Taking en passant takes
+// two pawns off one rank at once, which can uncover a check the pins do not show, so
+// each capture is tried on the board.
+func (b *Board) generateEnPassant(isWhite bool, moves *MoveList) {
+	if b.epSquare == 0 || b.pieceBitboard(Pawn, !isWhite)&enPassantVictim(b.epSquare, isWhite) == 0 {
+		return
+	}
+	// our pawns stand where an enemy pawn on the en passant square would attack
+	side := 0
+	if isWhite {
+		side = 1
+	}
+	takers := pawnAttackTable[side][bits.TrailingZeros64(b.epSquare)] & b.pieceBitboard(Pawn, isWhite)
+	for ; takers != 0; takers &= takers - 1 {
+		m := newMove(takers&-takers, b.epSquare, NoPiece, moveCapture|moveEnPassant)
+		undo := b.doMove(m, isWhite)
+		legal := !b.isCheck(isWhite)
+		b.undoMove(m, isWhite, undo)
+		if legal {
+			moves.add(m)
+		}
+	}
+}
+
+// canTakeEnPassant reports whether a pawn of the side to move attacks the en passant
+// square. Only then does the square tell the position apart from the same placement
+// without it.
+func (b *Board) canTakeEnPassant(whiteToMove bool) bool {
+	return b.epSquare != 0 && pawnAttacks(b.epSquare, !whiteToMove)&b.pieceBitboard(Pawn, whiteToMove) != 0
+}
+
+// hasLegalMove reports whether a side has any legal move.
+func (b *Board) hasLegalMove(isWhite bool) bool {
+	var moves MoveList
+	b.generateMoves(isWhite, &moves)
+	return moves.len() > 0
+}
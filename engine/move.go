@@ -0,0 +1,148 @@
+package engine
+
+import (
+	"fmt"
+	"math/bits"
+	"strings"
+)
+
+// Move is a move packed into 32 bits: the from square in bits 0-5, the to square in
+// bits 6-11, the piece a pawn promotes to in bits 12-14 and flags above them for the
+// moves that take more than one piece changing square. Castling is the king's move,
+// two files towards the rook.
+type Move uint32
+
+const (
+	moveCapture    Move = 1 << 16 // takes a piece, en passant included
+	moveEnPassant  Move = 1 << 17
+	moveCastle     Move = 1 << 18
+	moveDoublePush Move = 1 << 19
+
+	// noMove goes from h1 to h1, which no move does
+	noMove Move = 0
+)
+
+// promotionPieces are the pieces a pawn may promote to, numbered as in a Move.
+var promotionPieces = [...]PieceType{NoPiece, Knight, Bishop, Rook, Queen}
+
+// newMove packs a move from and to single-square bitboards.
+func newMove(from, to uint64, promotion PieceType, flags Move) Move {
+	m := Move(bits.TrailingZeros64(from)) | Move(bits.TrailingZeros64(to))<<6 | flags
+	for i, p := range promotionPieces {
+		if i > 0 && p == promotion {
+			m |= Move(i) << 12
+		}
+	}
+	return m
+}
+
+func (m Move) from() uint64 {
+	return 1 << (m & 63)
+}
+
+func (m Move) to() uint64 {
+	return 1 << (m >> 6 & 63)
+}
+
+// promotion returns the piece a pawn promotes to, or NoPiece.
+func (m Move) promotion() PieceType {
+	return promotionPieces[m>>12&7]
+}
+
+func (m Move) isCapture() bool {
+	return m&moveCapture != 0
+}
+
+func (m Move) isEnPassant() bool {
+	return m&moveEnPassant != 0
+}
+
+func (m Move) isCastle() bool {
+	return m&moveCastle != 0
+}
+
+func (m Move) isDoublePush() bool {
+	return m&moveDoublePush != 0
+}
+
+// String returns the move in UCI notation, e.g. e2e4, e1g1 or e7e8q.
+func (m Move) String() string {
+	if m == noMove {
+		return "0000"
+	}
+	s := uciSquare(m.from()) + uciSquare(m.to())
+	if promotion := m.promotion(); promotion != NoPiece {
+		s += strings.ToLower(string(rune(promotion)))
+	}
+	return s
+}
+
+// maxMoves is room for the moves of any position; the most known is 218.
+const maxMoves = 256
+
+// MoveList holds the moves of a position without allocating.
+type MoveList struct {
+	moves [maxMoves]Move
+	n     int
+}
+
+func (l *MoveList) add(m Move) {
+	l.moves[l.n] = m
+	l.n++
+}
+
+func (l *MoveList) len() int {
+	return l.n
+}
+
+// slice returns the moves in the list, backed by the list itself.
+func (l *MoveList) slice() []Move {
+	return l.moves[:l.n]
+}
+
+// findMove returns the legal move of the side to move from and to the given squares,
+// promoting to the given piece.
+func (g *Game) findMove(from, to uint64, promotion PieceType) (Move, bool) {
+	var moves MoveList
+	g.board.generateMoves(g.whiteToMove, &moves)
+	for _, m := range moves.slice() {
+		if m.from() == from && m.to() == to && m.promotion() == promotion {
+			return m, true
+		}
+	}
+	return noMove, false
+}
+
+// parseUCIMove finds the legal move a move in UCI notation (e.g. e2e4 or e7e8q)
+// stands for in the current position.
+func (g *Game) parseUCIMove(uci string) (Move, error) {
+	if (len(uci) != 4 && len(uci) != 5) || !isSquare(uci[0:2]) || !isSquare(uci[2:4]) {
+		return noMove, fmt.Errorf("invalid move %q", uci)
+	}
+	promotion := NoPiece
+	if len(uci) == 5 {
+		promotion = PieceType(strings.ToUpper(uci[4:])[0])
+	}
+	m, ok := g.findMove(g.board.notationToPos(uci[0:2]), g.board.notationToPos(uci[2:4]), promotion)
+	if !ok {
+		return noMove, fmt.Errorf("illegal move %q", uci)
+	}
+	return m, nil
+}
+
+// parseMove reads a move for the side to move in any of the notations the engine
+// takes: UCI (e2e4), our own piece-square form (Pe2-e4, Pe4xd5, Pe7-e8=Q, O-O, o-o)
+// or SAN.
+func (g *Game) parseMove(move string) (Move, error) {
+	switch {
+	case move == "o-o" || move == "o-o-o":
+		// black castling in our own notation
+		return g.parseSAN(strings.ToUpper(move))
+	case len(move) >= 6 && (move[3] == '-' || move[3] == 'x') && isSquare(move[1:3]) && isSquare(move[4:6]):
+		// a promotion is written after the squares, as in Pe7-e8=Q
+		return g.parseUCIMove(move[1:3] + move[4:6] + strings.ToLower(strings.TrimPrefix(move[6:], "=")))
+	case len(move) >= 4 && len(move) <= 5 && isSquare(move[0:2]) && isSquare(move[2:4]):
+		return g.parseUCIMove(move)
+	}
+	return g.parseSAN(move)
+}
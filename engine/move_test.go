@@ -0,0 +1,110 @@
+package engine
+
+import "testing"
+
+func TestMovePacking(t *testing.T) {
+	var b Board
+	for _, promotion := range promotionPieces {
+		for _, flags := range []Move{0, moveCapture, moveCapture | moveEnPassant, moveCastle, moveDoublePush} {
+			from, to := b.notationToPos("b7"), b.notationToPos("a8")
+			m := newMove(from, to, promotion, flags)
+			if m.from() != from || m.to() != to || m.promotion() != promotion {
+				t.Errorf("%v does not unpack to b7a8 promoting to %c", m, promotion)
+			}
+			if m.isCapture() != (flags&moveCapture != 0) || m.isEnPassant() != (flags&moveEnPassant != 0) ||
+				m.isCastle() != (flags&moveCastle != 0) || m.isDoublePush() != (flags&moveDoublePush != 0) {
+				t.Errorf("%v has the wrong flags", m)
+			}
+		}
+	}
+	if noMove.String() != "0000" {
+		t.Errorf("noMove is written %q", noMove.String())
+	}
+}
+
+func TestParseUCIMove(t *testing.T) {
+	tests := []struct {
+		position, uci string
+		flags         Move
+		ok            bool
+	}{
+		{"startpos", "e2e4", moveDoublePush, true},
+		{"startpos", "g1f3", 0, true},
+		{"startpos", "e2e5", 0, false},
+		{"startpos", "e7e5", 0, false},
+		{"startpos", "e2", 0, false},
+		{"startpos", "i2i4", 0, false},
+		{"startpos moves e2e4 d7d5", "e4d5", moveCapture, true},
+		{"startpos moves e2e4 a7a6 e4e5 d7d5", "e5d6", moveCapture | moveEnPassant, true},
+		{"fen r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "e1g1", moveCastle, true},
+		{"fen r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1", "e8c8", moveCastle, true},
+		{"fen r3k2r/8/8/8/8/8/8/R3K2R w Qkq - 0 1", "e1g1", 0, false},
+		{"fen 4k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "b7b8n", 0, true},
+		{"fen 4k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "b7b8", 0, false},
+		{"fen 4k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "b7b8k", 0, false},
+	}
+	for _, tt := range tests {
+		g := newTestGame(t, tt.position)
+		m, err := g.parseUCIMove(tt.uci)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: parsing %s gave %v, %v", tt.position, tt.uci, m, err)
+			continue
+		}
+		if tt.ok && (m.String() != tt.uci || m&^(1<<16-1) != tt.flags) {
+			t.Errorf("%s: %s parsed as %v with flags %x, want flags %x", tt.position, tt.uci, m, m&^(1<<16-1), tt.flags)
+		}
+	}
+}
+
+func TestParseMove(t *testing.T) {
+	tests := []struct {
+		position, move, want string
+	}{
+		{"startpos", "e2e4", "e2e4"},
+		{"startpos", "Pe2-e4", "e2e4"},
+		{"startpos", "Ng1-f3", "g1f3"},
+		{"startpos", "Nf3", "g1f3"},
+		{"startpos moves e2e4 d7d5", "Pe4xd5", "e4d5"},
+		{"fen 4k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "Pb7-b8=Q", "b7b8q"},
+		{"fen 4k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "b8=R", "b7b8r"},
+		{"fen r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1", "o-o", "e8g8"},
+		{"fen r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "O-O-O", "e1c1"},
+	}
+	for _, tt := range tests {
+		g := newTestGame(t, tt.position)
+		m, err := g.parseMove(tt.move)
+		if err != nil || m.String() != tt.want {
+			t.Errorf("%s: %s parsed as %v, %v; want %s", tt.position, tt.move, m, err, tt.want)
+		}
+	}
+}
+
+func TestPlaySpecialMoves(t *testing.T) {
+	tests := []struct {
+		position, want string
+	}{
+		{"fen r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1 moves e1g1", "r3k2r/8/8/8/8/8/8/R4RK1 b kq - 1 1"},
+		{"fen r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1 moves e8c8", "2kr3r/8/8/8/8/8/8/R3K2R w KQ - 1 2"},
+		{"fen r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1 moves a1a8", "R3k2r/8/8/8/8/8/8/4K2R b Kk - 0 1"},
+		{"startpos moves e2e4 a7a6 e4e5 d7d5", "rnbqkbnr/1pp1pppp/p7/3pP3/8/8/PPPP1PPP/RNBQKBNR w KQkq d6 0 3"},
+		{"startpos moves e2e4 a7a6 e4e5 d7d5 e5d6", "rnbqkbnr/1pp1pppp/p2P4/8/8/8/PPPP1PPP/RNBQKBNR b KQkq - 0 3"},
+		{"fen 1n2k3/P7/8/8/8/8/8/4K3 w - - 0 1 moves a7b8q", "1Q2k3/8/8/8/8/8/8/4K3 b - - 0 1"},
+	}
+	for _, tt := range tests {
+		g := newTestGame(t, tt.position)
+		if got := g.fen(); got != tt.want {
+			t.Errorf("%s: position %s, want %s", tt.position, got, tt.want)
+		}
+	}
+}
+
+func TestGenerateMovesDoesNotAllocate(t *testing.T) {
+	g := parseGame(perftPositions[1].Fen)
+	allocs := testing.AllocsPerRun(100, func() {
+		var moves MoveList
+		g.board.generateMoves(g.whiteToMove, &moves)
+	})
+	if allocs != 0 {
+		t.Errorf("move generation allocates %v times", allocs)
+	}
+}
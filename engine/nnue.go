@@ -0,0 +1,190 @@
+package engine
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+	"os"
+)
+
+// The network is a small efficiently updatable neural network: 768 inputs, one per
+// piece type, colour and square, feed a hidden layer of nnueHiddenSize neurons that
+// is computed once from each side's point of view. Both halves go through a clipped
+// ReLU, the side to move's half first, and a single output neuron gives the score.
+//
+// Because a move only switches a few inputs on or off, the hidden layer (the
+// accumulator) is kept on the board and updated by movePiece instead of being
+// computed again for every position.
+const (
+	nnueInputs     = 768
+	nnueHiddenSize = 128
+
+	nnueMagic   = "CKNN"
+	nnueVersion = 1
+
+	nnueQA    = 255 // fixed-point scale of the hidden layer
+	nnueQB    = 64  // fixed-point scale of the output weights
+	nnueScale = 400 // centipawns per unit of network output
+)
+
+// Network holds the weights of a network loaded from a file. It implements Evaluator.
+//
+// The file is little-endian: the magic "CKNN", the format version and the hidden layer
+// size as uint32, then the input weights as int16 (input by input), the hidden biases
+// as int16, the output weights as int16 (side to move's half first) and the output
+// bias as int32.
+type Network struct {
+	inputWeights  [nnueInputs][nnueHiddenSize]int16
+	hiddenBiases  [nnueHiddenSize]int16
+	outputWeights [2 * nnueHiddenSize]int16
+	outputBias    int32
+}
+
+// accumulator is the hidden layer of a position before activation, from white's
+// (index 0) and black's (index 1) point of view. It is only valid for the network
+// it was computed with; net is nil when it has not been computed.
+type accumulator struct {
+	net    *Network
+	values [2][nnueHiddenSize]int16
+}
+
+// LoadNetwork reads network weights from a file.
+func LoadNetwork(path string) (*Network, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	n := &Network{}
+	r := bufio.NewReader(file)
+	var header struct {
+		Magic   [4]byte
+		Version uint32
+		Hidden  uint32
+	}
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return nil, fmt.Errorf("reading network %s: %w", path, err)
+	}
+	if string(header.Magic[:]) != nnueMagic {
+		return nil, fmt.Errorf("reading network %s: not a network file", path)
+	}
+	if header.Version != nnueVersion || header.Hidden != nnueHiddenSize {
+		return nil, fmt.Errorf("reading network %s: unsupported version %d with %d hidden neurons", path, header.Version, header.Hidden)
+	}
+	for _, data := range []any{&n.inputWeights, &n.hiddenBiases, &n.outputWeights, &n.outputBias} {
+		if err := binary.Read(r, binary.LittleEndian, data); err != nil {
+			return nil, fmt.Errorf("reading network %s: %w", path, err)
+		}
+	}
+	if _, err := r.ReadByte(); !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("reading network %s: unexpected data after the weights", path)
+	}
+	return n, nil
+}
+
+// Save writes the network to a file that LoadNetwork can read back.
+func (n *Network) Save(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(file)
+	header := struct {
+		Magic   [4]byte
+		Version uint32
+		Hidden  uint32
+	}{Version: nnueVersion, Hidden: nnueHiddenSize}
+	copy(header.Magic[:], nnueMagic)
+	for _, data := range []any{&header, &n.inputWeights, &n.hiddenBiases, &n.outputWeights, &n.outputBias} {
+		if err := binary.Write(w, binary.LittleEndian, data); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func (n *Network) Name() string {
+	return "NNUE"
+}
+
+// Evaluate runs the network on the board, computing the accumulator first if it is
+// not up to date.
+func (n *Network) Evaluate(b *Board, whiteToMove bool) Score {
+	if b.acc.net != n {
+		n.refresh(b)
+	}
+	us, them := &b.acc.values[0], &b.acc.values[1]
+	if !whiteToMove {
+		us, them = them, us
+	}
+	output := int64(0)
+	for i := 0; i < nnueHiddenSize; i++ {
+		output += int64(crelu(us[i])) * int64(n.outputWeights[i])
+		output += int64(crelu(them[i])) * int64(n.outputWeights[nnueHiddenSize+i])
+	}
+	output += int64(n.outputBias)
+	score := Score(output * nnueScale / (nnueQA * nnueQB))
+	if !whiteToMove {
+		score = -score
+	}
+	return max(min(score, scoreMateBound-1), -scoreMateBound+1)
+}
+
+func crelu(v int16) int32 {
+	return int32(max(min(v, nnueQA), 0))
+}
+
+// nnueInput returns the input for a piece on a square seen from one side: that side's
+// pieces come first, and black sees the board flipped vertically.
+func nnueInput(perspective int, sq int, pieceType PieceType, isWhite bool) int {
+	colour := colourIndex(isWhite)
+	if perspective == 1 {
+		sq ^= 56
+	}
+	if colour != perspective {
+		return 384 + pieceIndex(pieceType)*64 + sq
+	}
+	return pieceIndex(pieceType)*64 + sq
+}
+
+// refresh computes the accumulator of a board from scratch.
+func (n *Network) refresh(b *Board) {
+	b.acc.net = n
+	b.acc.values[0], b.acc.values[1] = n.hiddenBiases, n.hiddenBiases
+	for colour := range b.pieces {
+		for idx, bb := range b.pieces[colour] {
+			for ; bb != 0; bb &= bb - 1 {
+				n.update(&b.acc, 0, bb&-bb, pieceTypes[idx], colour == whiteIndex)
+			}
+		}
+	}
+}
+
+// update moves a piece in the accumulator. Either square may be 0, for a piece that
+// is added to or removed from the board.
+func (n *Network) update(acc *accumulator, initPos, finalPos uint64, pieceType PieceType, isWhite bool) {
+	for perspective := 0; perspective < 2; perspective++ {
+		values := &acc.values[perspective]
+		if initPos != 0 {
+			weights := &n.inputWeights[nnueInput(perspective, bits.TrailingZeros64(initPos), pieceType, isWhite)]
+			for i := range values {
+				values[i] -= weights[i]
+			}
+		}
+		if finalPos != 0 {
+			weights := &n.inputWeights[nnueInput(perspective, bits.TrailingZeros64(finalPos), pieceType, isWhite)]
+			for i := range values {
+				values[i] += weights[i]
+			}
+		}
+	}
+}
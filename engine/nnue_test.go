@@ -0,0 +1,140 @@
+package engine
+
+import (
+	"flag"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "write the generated files under testdata again")
+
+const tinyNetworkPath = "testdata/tiny.nnue"
+
+// tinyNetwork returns the network stored in testdata: random weights small enough
+// that no accumulator overflows, with biases that leave some neurons clipped at
+// either end. Run go test -run TestTinyNetwork -update to write the file again.
+func tinyNetwork() *Network {
+	r := rand.New(rand.NewSource(1))
+	n := &Network{}
+	for i := range n.inputWeights {
+		for j := range n.inputWeights[i] {
+			n.inputWeights[i][j] = int16(r.Intn(33) - 16)
+		}
+	}
+	for i := range n.hiddenBiases {
+		n.hiddenBiases[i] = int16(r.Intn(301) - 50)
+	}
+	for i := range n.outputWeights {
+		n.outputWeights[i] = int16(r.Intn(129) - 64)
+	}
+	n.outputBias = int32(r.Intn(2001) - 1000)
+	return n
+}
+
+func loadTinyNetwork(t *testing.T) *Network {
+	t.Helper()
+	net, err := LoadNetwork(tinyNetworkPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return net
+}
+
+func TestTinyNetwork(t *testing.T) {
+	if *update {
+		if err := tinyNetwork().Save(tinyNetworkPath); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !reflect.DeepEqual(loadTinyNetwork(t), tinyNetwork()) {
+		t.Errorf("%s does not hold the generated network", tinyNetworkPath)
+	}
+}
+
+func TestLoadNetworkRejectsBadFiles(t *testing.T) {
+	data, err := os.ReadFile(tinyNetworkPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bad := map[string][]byte{
+		"magic":     append([]byte("XXXX"), data[4:]...),
+		"version":   append(append([]byte(nil), data[:4]...), append([]byte{2, 0, 0, 0}, data[8:]...)...),
+		"truncated": data[:len(data)-1],
+		"trailing":  append(append([]byte(nil), data...), 0),
+	}
+	for name, file := range bad {
+		path := filepath.Join(t.TempDir(), name+".nnue")
+		if err := os.WriteFile(path, file, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadNetwork(path); err == nil {
+			t.Errorf("network with bad %s was accepted", name)
+		}
+	}
+}
+
+// TestNetworkIncrementalUpdates plays random games and takes them back, checking at
+// every step that the accumulator kept up to date by the moves matches one computed
+// from scratch, and so does the score.
+func TestNetworkIncrementalUpdates(t *testing.T) {
+	net := loadTinyNetwork(t)
+	r := rand.New(rand.NewSource(1))
+	check := func(fen string, b *Board, whiteToMove bool) {
+		t.Helper()
+		scratch := *b
+		scratch.acc = accumulator{}
+		want := net.Evaluate(&scratch, whiteToMove)
+		if b.acc.net != net || b.acc.values != scratch.acc.values {
+			t.Fatalf("%s: accumulator differs from one computed from scratch", fen)
+		}
+		if got := net.Evaluate(b, whiteToMove); got != want {
+			t.Fatalf("%s: incremental score %d, from scratch %d", fen, got, want)
+		}
+	}
+
+	fens := evalTestFens
+	for _, p := range perftPositions {
+		fens = append(fens, p.Fen)
+	}
+	for _, fen := range fens {
+		g := parseGame(fen)
+		b, isWhite := &g.board, g.whiteToMove
+		net.refresh(b)
+
+		type played struct {
+			move Move
+			undo moveUndo
+		}
+		var line []played
+		for ply := 0; ply < 60; ply++ {
+			var moves MoveList
+			b.generateMoves(isWhite, &moves)
+			if moves.len() == 0 {
+				break
+			}
+			m := moves.slice()[r.Intn(moves.len())]
+			line = append(line, played{m, b.doMove(m, isWhite)})
+			isWhite = !isWhite
+			check(fen, b, isWhite)
+		}
+		for i := len(line) - 1; i >= 0; i-- {
+			isWhite = !isWhite
+			b.undoMove(line[i].move, isWhite, line[i].undo)
+			check(fen, b, isWhite)
+		}
+	}
+}
+
+func TestNetworkIsSymmetric(t *testing.T) {
+	net := loadTinyNetwork(t)
+	for _, fen := range evalTestFens {
+		g, mirrored := parseGame(fen), parseGame(mirrorFen(fen))
+		score := net.Evaluate(&g.board, g.whiteToMove)
+		if got := net.Evaluate(&mirrored.board, mirrored.whiteToMove); got != -score {
+			t.Errorf("%s: network scores %d, mirrored %d", fen, score, got)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package engine
 
 import (
+	"math/bits"
 	"strconv"
 	"strings"
 	"unicode"
@@ -8,23 +9,7 @@ import (
 
 // Init initializes the chess board with the starting positions of the pieces.
 func (b *Board) Empty() {
-	b.whitePawns = 0x0000000000000000
-	b.whiteKnights = 0x0000000000000000
-	b.whiteBishops = 0x0000000000000000
-	b.whiteRooks = 0x0000000000000000
-	b.whiteQueens = 0x0000000000000000
-	b.whiteKing = 0x0000000000000000
-	b.whitePieces = 0x0000000000000000
-
-	b.blackPawns = 0x0000000000000000
-	b.blackKnights = 0x0000000000000000
-	b.blackBishops = 0x0000000000000000
-	b.blackRooks = 0x0000000000000000
-	b.blackQueens = 0x0000000000000000
-	b.blackKing = 0x0000000000000000
-	b.blackPieces = 0x0000000000000000
-
-	b.allPieces = 0x0000000000000000
+	*b = Board{}
 }
 
 func parse(fen string) Board {
@@ -63,23 +48,14 @@ func (b *Board) piecePosToNotation(pos uint64) string {
 }
 
 func (b *Board) posToNotation(pos uint64) string {
-	var posNotation string = ""
-	for i := 63; i >= 0; i-- {
-		var cur_pos uint64 = 1 << uint64(i)
-		if pos&cur_pos != 0 {
-			file := int((63 - i) % 8)
-			rank := int((i) / 8)
-			posNotation = string(rune(97+file)) + string(rune(49+rank))
-			break
-		}
+	if pos == 0 {
+		return ""
 	}
-	return posNotation
-}
-
-func flipNotation(notation string) string {
-	file := int(notation[0] - 97)
-	rank := int(notation[1] - 49)
-	return string(rune(97+7-file)) + string(rune(49+7-rank))
+	// the highest square set, as a-file squares sit above the h-file ones
+	i := 63 - bits.LeadingZeros64(pos)
+	file := int((63 - i) % 8)
+	rank := int((i) / 8)
+	return string(rune(97+file)) + string(rune(49+rank))
 }
 
 func (b *Board) notationToPos(notation string) uint64 {
@@ -92,17 +68,3 @@ func (b *Board) notationToPos(notation string) uint64 {
 func (b *Board) notationToMove(notation string) (PieceType, uint64, uint64) {
 	return PieceType(rune(notation[0])), b.notationToPos(notation[1:3]), b.notationToPos(notation[4:])
 }
-
-func (b *Board) moveToNotation(isWhite bool, initPos, finalPos uint64, pieceType PieceType, wasPieceCaptured bool) string {
-	initPosNot := b.posToNotation(initPos)
-	finalPosNot := b.posToNotation(finalPos)
-	if !isWhite {
-		initPosNot = flipNotation(initPosNot)
-		finalPosNot = flipNotation(finalPosNot)
-	}
-	var sep string = "-"
-	if wasPieceCaptured {
-		sep = "x"
-	}
-	return string(rune(pieceType)) + initPosNot + sep + finalPosNot
-}
@@ -0,0 +1,184 @@
+package engine
+
+import "math/bits"
+
+// scorePair holds the midgame and endgame value of an evaluation term, blended by taper.
+type scorePair struct {
+	mg, eg int
+}
+
+func (s scorePair) add(o scorePair) scorePair {
+	return scorePair{s.mg + o.mg, s.eg + o.eg}
+}
+
+func (s scorePair) sub(o scorePair) scorePair {
+	return scorePair{s.mg - o.mg, s.eg - o.eg}
+}
+
+func (s scorePair) scale(n int) scorePair {
+	return scorePair{s.mg * n, s.eg * n}
+}
+
+var (
+	// fileMasks[f] is every square of file f, a-file first
+	fileMasks [8]uint64
+	// adjacentFileMasks[f] is every square of the files next to file f
+	adjacentFileMasks [8]uint64
+	// passedPawnMasks[colour][sq] is the squares in front of a pawn on sq, on its own
+	// and adjacent files, that must be free of enemy pawns for it to be passed
+	passedPawnMasks [2][64]uint64
+	// forwardMasks[colour][sq] is the squares straight in front of sq
+	forwardMasks [2][64]uint64
+)
+
+func init() {
+	for f := 0; f < 8; f++ {
+		fileMasks[f] = leftEdge >> uint(f)
+	}
+	for f := 0; f < 8; f++ {
+		if f > 0 {
+			adjacentFileMasks[f] |= fileMasks[f-1]
+		}
+		if f < 7 {
+			adjacentFileMasks[f] |= fileMasks[f+1]
+		}
+	}
+	for sq := 0; sq < 64; sq++ {
+		f, r := squareFile(sq), squareRank(sq)
+		var ahead, behind uint64
+		for rank := 0; rank < 8; rank++ {
+			if rank > r {
+				ahead |= bottomEdge << uint(8*rank)
+			} else if rank < r {
+				behind |= bottomEdge << uint(8*rank)
+			}
+		}
+		forwardMasks[0][sq] = ahead & fileMasks[f]
+		forwardMasks[1][sq] = behind & fileMasks[f]
+		passedPawnMasks[0][sq] = ahead & (fileMasks[f] | adjacentFileMasks[f])
+		passedPawnMasks[1][sq] = behind & (fileMasks[f] | adjacentFileMasks[f])
+	}
+}
+
+// squareFile returns the file of a square index, 0 for the a-file.
+func squareFile(sq int) int {
+	return 7 - sq%8
+}
+
+// squareRank returns the rank of a square index, 0 for the first rank.
+func squareRank(sq int) int {
+	return sq / 8
+}
+
+// relativeRank returns the rank of a square counted from the given side's back rank.
+func relativeRank(sq int, isWhite bool) int {
+	if isWhite {
+		return squareRank(sq)
+	}
+	return 7 - squareRank(sq)
+}
+
+// colourIndex maps a side to the index used by per-colour tables and the board.
+func colourIndex(isWhite bool) int {
+	if isWhite {
+		return whiteIndex
+	}
+	return blackIndex
+}
+
+// pawnEntry caches the pawn structure of one pawn placement.
+type pawnEntry struct {
+	key    uint64
+	scores [2]scorePair // white, black
+	passed [2]uint64    // passed pawns of each side
+}
+
+// pawnTable caches pawn structure scores by pawn hash. Pawns move rarely, so most
+// positions in a search share their pawn structure with many others.
+var pawnTable [1 << 14]pawnEntry
+
+// evalPawnStructure scores passed, isolated, doubled and backward pawns for each side.
+func (b *Board) evalPawnStructure() (scorePair, scorePair) {
+	entry := &pawnTable[b.pawnHash&uint64(len(pawnTable)-1)]
+	if entry.key != b.pawnHash {
+		entry.key = b.pawnHash
+		entry.scores[0], entry.passed[0] = evalPawns(b.pieces[whiteIndex][pawnIndex], b.pieces[blackIndex][pawnIndex], true)
+		entry.scores[1], entry.passed[1] = evalPawns(b.pieces[blackIndex][pawnIndex], b.pieces[whiteIndex][pawnIndex], false)
+	}
+
+	// whether a passed pawn is blockaded depends on the other pieces, so it is not cached
+	white := entry.scores[0].sub(b.passedPawnBlockade(entry.passed[0], true))
+	black := entry.scores[1].sub(b.passedPawnBlockade(entry.passed[1], false))
+	return white, black
+}
+
+// evalPawns scores the pawn structure of one side and returns its passed pawns.
+func evalPawns(ownPawns, enemyPawns uint64, isWhite bool) (scorePair, uint64) {
+	var score scorePair
+	var passed uint64
+	colour := colourIndex(isWhite)
+	ownAttacks := pawnAttacks(ownPawns, isWhite)
+	enemyAttacks := pawnAttacks(enemyPawns, !isWhite)
+
+	for f := 0; f < 8; f++ {
+		if count := bits.OnesCount64(ownPawns & fileMasks[f]); count > 1 {
+			score = score.add(evalParams.DoubledPawn.scale(count - 1))
+		}
+	}
+
+	for pawns := ownPawns; pawns != 0; pawns &= pawns - 1 {
+		pos := pawns & -pawns
+		sq := bits.TrailingZeros64(pos)
+		f := squareFile(sq)
+
+		if passedPawnMasks[colour][sq]&enemyPawns == 0 && forwardMasks[colour][sq]&ownPawns == 0 {
+			passed |= pos
+			bonus := evalParams.PassedPawn[relativeRank(sq, isWhite)]
+			if pos&ownAttacks != 0 {
+				// a defended passer can only be stopped by pieces
+				bonus = bonus.add(scorePair{bonus.mg / 2, bonus.eg / 2})
+			}
+			score = score.add(bonus)
+		}
+
+		neighbours := ownPawns & adjacentFileMasks[f]
+		if neighbours == 0 {
+			score = score.add(evalParams.IsolatedPawn)
+			continue
+		}
+		// backward: every neighbour has already moved past it, so no pawn can defend
+		// it, and advancing it would walk into an enemy pawn's attack
+		if neighbours&^passedPawnMasks[colour][sq] == 0 {
+			var stop uint64
+			if isWhite {
+				stop = pos << 8
+			} else {
+				stop = pos >> 8
+			}
+			if stop&enemyAttacks != 0 {
+				score = score.add(evalParams.BackwardPawn)
+			}
+		}
+	}
+	return score, passed
+}
+
+// passedPawnBlockade returns how much of the passed pawn bonus is lost to pieces
+// standing right in front of the passed pawns: half of it for each blockaded pawn.
+func (b *Board) passedPawnBlockade(passed uint64, isWhite bool) scorePair {
+	var loss scorePair
+	for ; passed != 0; passed &= passed - 1 {
+		pos := passed & -passed
+		var stop uint64
+		if isWhite {
+			stop = pos << 8
+		} else {
+			stop = pos >> 8
+		}
+		if stop&b.allPieces != 0 {
+			bonus := evalParams.PassedPawn[relativeRank(bits.TrailingZeros64(pos), isWhite)]
+			loss = loss.add(scorePair{bonus.mg / 2, bonus.eg / 2})
+		}
+	}
+	return loss
+}
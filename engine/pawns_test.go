@@ -0,0 +1,69 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEvalPawns(t *testing.T) {
+	p := &evalParams
+	half := func(s scorePair) scorePair { return scorePair{s.mg / 2, s.eg / 2} }
+	tests := []struct {
+		name   string
+		fen    string
+		score  scorePair
+		passed string
+	}{
+		{"isolated", "4k3/ppp5/8/8/8/8/P1P5/4K3 w - - 0 1", p.IsolatedPawn.scale(2), ""},
+		{"doubled", "4k3/1ppp4/8/8/8/2P5/2P5/4K3 w - - 0 1", p.DoubledPawn.add(p.IsolatedPawn.scale(2)), ""},
+		{"defended passer", "4k3/p7/8/4P3/5P2/8/8/4K3 w - - 0 1", p.PassedPawn[4].add(half(p.PassedPawn[4])).add(p.PassedPawn[3]), "e5 f4"},
+		{"backward", "4k3/8/8/2p1p3/4P3/3P4/8/4K3 w - - 0 1", p.BackwardPawn, ""},
+	}
+	for _, tt := range tests {
+		b := testBoard(tt.fen)
+		score, passed := evalPawns(b.pieces[whiteIndex][pawnIndex], b.pieces[blackIndex][pawnIndex], true)
+		if score != tt.score {
+			t.Errorf("%s: score %v, want %v", tt.name, score, tt.score)
+		}
+		var want uint64
+		for _, sq := range strings.Fields(tt.passed) {
+			want |= b.notationToPos(sq)
+		}
+		if passed != want {
+			t.Errorf("%s: passed pawns %x, want %x", tt.name, passed, want)
+		}
+	}
+}
+
+func TestPassedPawnBlockade(t *testing.T) {
+	free := testBoard("4k3/8/8/8/4P3/8/8/4K3 w - - 0 1")
+	blocked := testBoard("4k3/8/8/4n3/4P3/8/8/4K3 w - - 0 1")
+	freeScore, _ := free.evalPawnStructure()
+	blockedScore, _ := blocked.evalPawnStructure()
+	bonus := evalParams.PassedPawn[3]
+	if want := freeScore.sub(scorePair{bonus.mg / 2, bonus.eg / 2}); blockedScore != want {
+		t.Errorf("blockaded passer scores %v, want %v", blockedScore, want)
+	}
+}
+
+func TestPawnTableFollowsPawnHash(t *testing.T) {
+	g := newTestGame(t, "startpos moves e2e4 e7e5 g1f3")
+	b := &g.board
+	white, black := b.evalPawnStructure()
+	// the cached entry matches a fresh evaluation of the same pawns
+	pawnTable = [len(pawnTable)]pawnEntry{}
+	if w, bl := b.evalPawnStructure(); w != white || bl != black {
+		t.Errorf("cached pawn scores %v %v, fresh %v %v", white, black, w, bl)
+	}
+	before := b.pawnHash
+	m, _ := g.parseUCIMove("b8c6")
+	g.play(m)
+	if b.pawnHash != before {
+		t.Error("a knight move changed the pawn hash")
+	}
+	m, _ = g.parseUCIMove("d2d4")
+	g.play(m)
+	if b.pawnHash == before {
+		t.Error("a pawn move left the pawn hash unchanged")
+	}
+}
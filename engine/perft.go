@@ -0,0 +1,75 @@
+package engine
+
+import "errors"
+
+// PerftPosition is a test position with its published perft counts, Nodes[d-1] being
+// the count at depth d.
+type PerftPosition struct {
+	Fen   string
+	Nodes []uint64
+}
+
+// perftPositions are the usual perft test positions, full of castling, en passant,
+// promotions, pins and discovered checks.
+var perftPositions = []PerftPosition{
+	{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", []uint64{20, 400, 8902, 197281, 4865609}},
+	{"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", []uint64{48, 2039, 97862, 4085603}},
+	{"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1", []uint64{14, 191, 2812, 43238, 674624}},
+	{"r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1", []uint64{6, 264, 9467, 422333}},
+	{"rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ - 1 8", []uint64{44, 1486, 62379, 2103487}},
+	{"r4rk1/1pp1qppp/p1np1n2/2b1p1B1/2B1P1b1/P1NP1N2/1PP1QPPP/R4RK1 w - - 0 10", []uint64{46, 2079, 89890, 3894594}},
+}
+
+// PerftPositions returns the positions the perft suite runs on.
+func PerftPositions() []PerftPosition {
+	return append([]PerftPosition(nil), perftPositions...)
+}
+
+// PerftDivide is the number of leaf nodes below one root move.
+type PerftDivide struct {
+	Move  string
+	Nodes uint64
+}
+
+// Perft counts the positions reached after depth plies from a position, split by
+// root move.
+func Perft(fen string, depth int) ([]PerftDivide, error) {
+	if depth < 1 {
+		return nil, errors.New("depth must be at least 1")
+	}
+	g := parseGame(fen)
+	b := &g.board
+	if b.pieces[whiteIndex][kingIndex] == 0 || b.pieces[blackIndex][kingIndex] == 0 {
+		return nil, errors.New("invalid fen: both sides need a king")
+	}
+	isWhite := g.whiteToMove
+
+	var moves MoveList
+	b.generateMoves(isWhite, &moves)
+	var divide []PerftDivide
+	for _, move := range moves.slice() {
+		undo := b.doMove(move, isWhite)
+		divide = append(divide, PerftDivide{move.String(), b.perft(!isWhite, depth-1)})
+		b.undoMove(move, isWhite, undo)
+	}
+	return divide, nil
+}
+
+// perft counts the leaf nodes of the move tree below the board.
+func (b *Board) perft(isWhite bool, depth int) uint64 {
+	if depth == 0 {
+		return 1
+	}
+	var moves MoveList
+	b.generateMoves(isWhite, &moves)
+	if depth == 1 {
+		return uint64(moves.len())
+	}
+	var nodes uint64
+	for _, move := range moves.slice() {
+		undo := b.doMove(move, isWhite)
+		nodes += b.perft(!isWhite, depth-1)
+		b.undoMove(move, isWhite, undo)
+	}
+	return nodes
+}
@@ -0,0 +1,45 @@
+package engine
+
+import "testing"
+
+func TestPerft(t *testing.T) {
+	maxDepth := 4
+	if testing.Short() {
+		maxDepth = 3
+	}
+	for _, p := range PerftPositions() {
+		for depth := 1; depth <= min(maxDepth, len(p.Nodes)); depth++ {
+			divide, err := Perft(p.Fen, depth)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var nodes uint64
+			for _, d := range divide {
+				nodes += d.Nodes
+			}
+			if nodes != p.Nodes[depth-1] {
+				t.Errorf("%s: perft %d is %d, want %d", p.Fen, depth, nodes, p.Nodes[depth-1])
+			}
+		}
+	}
+}
+
+func TestPerftLeavesBoardUnchanged(t *testing.T) {
+	for _, p := range PerftPositions() {
+		g := parseGame(p.Fen)
+		before := g.board
+		g.board.perft(g.whiteToMove, 3)
+		if g.board != before {
+			t.Errorf("%s: board changed by perft", p.Fen)
+		}
+	}
+}
+
+func TestPerftErrors(t *testing.T) {
+	if _, err := Perft(startPosition, 0); err == nil {
+		t.Error("perft to depth 0 was accepted")
+	}
+	if _, err := Perft("8/8/8/8/8/8/8/4K3 w - - 0 1", 1); err == nil {
+		t.Error("perft without a black king was accepted")
+	}
+}
@@ -0,0 +1,627 @@
+package engine
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// pgnGame is a game read from a PGN file: its tag pairs, its main line and its result.
+type pgnGame struct {
+	tags   map[string]string
+	moves  []pgnMove
+	result string
+}
+
+// pgnMove is a move of a PGN game in SAN, with what the movetext says about it.
+type pgnMove struct {
+	san        string
+	before     string // a comment before the move, kept at the start of a line
+	notes      pgnNotes
+	variations [][]pgnMove // lines played instead of this move
+}
+
+// pgnNotes is what the movetext says after a move.
+type pgnNotes struct {
+	nags    []int  // numeric annotation glyphs, with !, ?, !!, ??, !? and ?! read as $1 to $6
+	comment string // the comments, without the [%eval] and [%clk] commands
+	eval    string // the [%eval] command, e.g. 0.35 or #-4, from white's point of view
+	clock   string // the [%clk] command, the time left on the mover's clock, e.g. 1:05:30
+}
+
+var (
+	pgnTag        = regexp.MustCompile(`^\[(\w+)\s+"(.*)"\]$`)
+	pgnMoveNumber = regexp.MustCompile(`^\d+\.+`)
+	pgnCommand    = regexp.MustCompile(`\[%(eval|clk)\s+([^\]]*)\]`)
+
+	pgnSuffixes = map[string]int{"!": 1, "?": 2, "!!": 3, "??": 4, "!?": 5, "?!": 6}
+
+	// pgnRoster is the seven tag roster, which every exported game starts with
+	pgnRoster = []string{"Event", "Site", "Date", "Round", "White", "Black", "Result"}
+)
+
+// readPGN reads every game in a PGN stream, with the comments, annotations and
+// variations of each move.
+func readPGN(r io.Reader) ([]pgnGame, error) {
+	var p pgnReader
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
+	for scanner.Scan() {
+		p.readLine(scanner.Text())
+	}
+	p.finish()
+	return p.games, scanner.Err()
+}
+
+// pgnReader holds what readPGN has read so far.
+type pgnReader struct {
+	games     []pgnGame
+	game      *pgnGame
+	lines     []*[]pgnMove // the main line and the variations open in it, innermost last
+	inComment bool         // inside a {comment}, which may span lines
+	comment   strings.Builder
+	before    string // a comment waiting for the first move of a line
+}
+
+func (p *pgnReader) readLine(line string) {
+	trimmed := strings.TrimSpace(line)
+	if !p.inComment && strings.HasPrefix(trimmed, "%") {
+		return // escaped line
+	}
+	if !p.inComment && len(p.lines) <= 1 && strings.HasPrefix(trimmed, "[") {
+		if p.game != nil && len(p.game.moves) > 0 {
+			p.finish()
+		}
+		p.start()
+		if m := pgnTag.FindStringSubmatch(trimmed); m != nil {
+			p.game.tags[m[1]] = strings.NewReplacer(`\"`, `"`, `\\`, `\`).Replace(m[2])
+		}
+		return
+	}
+	if trimmed == "" && !p.inComment {
+		return
+	}
+	for rest := line; rest != ""; {
+		p.start() // a result ends the game, whatever follows on the line
+		if p.inComment {
+			text, after, closed := strings.Cut(rest, "}")
+			p.comment.WriteString(text + " ")
+			if !closed {
+				return
+			}
+			p.inComment = false
+			p.addComment(p.comment.String())
+			p.comment.Reset()
+			rest = after
+			continue
+		}
+		rest = strings.TrimLeft(rest, " \t\r")
+		if rest == "" {
+			break
+		}
+		switch rest[0] {
+		case '{':
+			p.inComment = true
+		case ';':
+			p.addComment(rest[1:]) // the rest of the line is a comment
+			return
+		case '(':
+			p.openVariation()
+		case ')':
+			p.closeVariation()
+		default:
+			n := strings.IndexAny(rest, " \t\r{};()")
+			if n < 0 {
+				n = len(rest)
+			}
+			p.readToken(rest[:n])
+			rest = rest[n:]
+			continue
+		}
+		rest = rest[1:]
+	}
+}
+
+// start begins a game unless one is being read.
+func (p *pgnReader) start() {
+	if p.game == nil {
+		p.game = &pgnGame{tags: map[string]string{}}
+		p.lines = []*[]pgnMove{&p.game.moves}
+	}
+}
+
+// finish adds the game being read, if any, to the games read.
+func (p *pgnReader) finish() {
+	if p.game != nil && (len(p.game.moves) > 0 || len(p.game.tags) > 0) {
+		if p.game.result == "" {
+			p.game.result = p.game.tags["Result"]
+		}
+		p.games = append(p.games, *p.game)
+	}
+	p.game, p.lines, p.inComment, p.before = nil, nil, false, ""
+	p.comment.Reset()
+}
+
+// line returns the line moves are being added to.
+func (p *pgnReader) line() *[]pgnMove {
+	return p.lines[len(p.lines)-1]
+}
+
+// lastMove returns the move annotations go to, or nil at the start of a line.
+func (p *pgnReader) lastMove() *pgnMove {
+	line := p.line()
+	if len(*line) == 0 {
+		return nil
+	}
+	return &(*line)[len(*line)-1]
+}
+
+func (p *pgnReader) readToken(token string) {
+	switch {
+	case token == "1-0" || token == "0-1" || token == "1/2-1/2" || token == "*":
+		if len(p.lines) == 1 {
+			p.game.result = token
+			p.finish()
+		}
+	case strings.HasPrefix(token, "$"):
+		if nag, err := strconv.Atoi(token[1:]); err == nil {
+			p.addNAG(nag)
+		}
+	default:
+		move := strings.TrimLeft(pgnMoveNumber.ReplaceAllString(token, ""), ".")
+		san := strings.TrimRight(move, "!?")
+		if san != "" {
+			*p.line() = append(*p.line(), pgnMove{san: san, before: p.before})
+			p.before = ""
+		}
+		if nag, ok := pgnSuffixes[move[len(san):]]; ok {
+			p.addNAG(nag)
+		}
+	}
+}
+
+func (p *pgnReader) addNAG(nag int) {
+	if m := p.lastMove(); m != nil {
+		m.notes.nags = append(m.notes.nags, nag)
+	}
+}
+
+// addComment gives a comment to the last move, taking the eval and clock commands
+// out of it, or keeps it for the next move at the start of a line.
+func (p *pgnReader) addComment(text string) {
+	m := p.lastMove()
+	if m == nil {
+		p.before = joinComments(p.before, text)
+		return
+	}
+	for _, c := range pgnCommand.FindAllStringSubmatch(text, -1) {
+		if c[1] == "eval" {
+			m.notes.eval = strings.TrimSpace(c[2])
+		} else {
+			m.notes.clock = strings.TrimSpace(c[2])
+		}
+	}
+	m.notes.comment = joinComments(m.notes.comment, pgnCommand.ReplaceAllString(text, ""))
+}
+
+// openVariation starts a line played instead of the last move.
+func (p *pgnReader) openVariation() {
+	m := p.lastMove()
+	if m == nil {
+		// nothing to be played instead of, so the variation is read and dropped
+		p.lines = append(p.lines, &[]pgnMove{})
+		return
+	}
+	m.variations = append(m.variations, nil)
+	p.lines = append(p.lines, &m.variations[len(m.variations)-1])
+}
+
+func (p *pgnReader) closeVariation() {
+	if len(p.lines) > 1 {
+		p.lines = p.lines[:len(p.lines)-1]
+	}
+	p.before = ""
+}
+
+// joinComments adds a comment to another, with the whitespace of both tidied up.
+func joinComments(comment, text string) string {
+	return strings.Join(strings.Fields(comment+" "+text), " ")
+}
+
+// writePGN writes a game in PGN export format: the seven tag roster first, then the
+// other tags in alphabetical order, then the movetext wrapped at 80 columns.
+func writePGN(w io.Writer, game pgnGame) error {
+	result := game.result
+	if result == "" {
+		result = "*"
+	}
+	var sb strings.Builder
+	escape := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	for _, name := range pgnRoster {
+		value, ok := game.tags[name]
+		switch {
+		case name == "Result":
+			value = result
+		case !ok && name == "Date":
+			value = "????.??.??"
+		case !ok:
+			value = "?"
+		}
+		fmt.Fprintf(&sb, "[%s \"%s\"]\n", name, escape.Replace(value))
+	}
+	var others []string
+	for name := range game.tags {
+		if !isRosterTag(name) {
+			others = append(others, name)
+		}
+	}
+	sort.Strings(others)
+	for _, name := range others {
+		fmt.Fprintf(&sb, "[%s \"%s\"]\n", name, escape.Replace(game.tags[name]))
+	}
+	sb.WriteString("\n")
+
+	start := gameFromTags(game.tags)
+	var tokens []string
+	writePGNLine(&tokens, game.moves, start.fullmoveNumber, start.whiteToMove)
+	tokens = append(tokens, result)
+	width := 0
+	for _, word := range strings.Fields(strings.Join(tokens, " ")) {
+		if width > 0 && width+1+len(word) > 80 {
+			sb.WriteString("\n")
+			width = 0
+		} else if width > 0 {
+			sb.WriteString(" ")
+			width++
+		}
+		sb.WriteString(word)
+		width += len(word)
+	}
+	sb.WriteString("\n\n")
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// writePGNLine adds the movetext of a line of moves to tokens, numbering the moves
+// from the given move on. Black's moves are numbered too after anything that breaks
+// up the line, such as a comment or a variation.
+func writePGNLine(tokens *[]string, moves []pgnMove, number int, whiteToMove bool) {
+	numbered := false
+	for _, m := range moves {
+		if m.before != "" {
+			*tokens = append(*tokens, "{"+m.before+"}")
+			numbered = false
+		}
+		if whiteToMove {
+			*tokens = append(*tokens, strconv.Itoa(number)+".")
+		} else if !numbered {
+			*tokens = append(*tokens, strconv.Itoa(number)+"...")
+		}
+		numbered = true
+		*tokens = append(*tokens, m.san)
+		for _, nag := range m.notes.nags {
+			*tokens = append(*tokens, "$"+strconv.Itoa(nag))
+		}
+		if comment := m.notes.String(); comment != "" {
+			*tokens = append(*tokens, "{"+comment+"}")
+			numbered = false
+		}
+		for _, variation := range m.variations {
+			var sub []string
+			writePGNLine(&sub, variation, number, whiteToMove)
+			if len(sub) > 0 {
+				sub[0] = "(" + sub[0]
+				sub[len(sub)-1] += ")"
+				*tokens = append(*tokens, sub...)
+				numbered = false
+			}
+		}
+		if !whiteToMove {
+			number++
+		}
+		whiteToMove = !whiteToMove
+	}
+}
+
+// String writes the notes as the text of a comment, with the eval and clock commands
+// first.
+func (n pgnNotes) String() string {
+	var parts []string
+	if n.eval != "" {
+		parts = append(parts, "[%eval "+n.eval+"]")
+	}
+	if n.clock != "" {
+		parts = append(parts, "[%clk "+n.clock+"]")
+	}
+	if n.comment != "" {
+		parts = append(parts, n.comment)
+	}
+	return strings.Join(parts, " ")
+}
+
+func isRosterTag(name string) bool {
+	for _, roster := range pgnRoster {
+		if name == roster {
+			return true
+		}
+	}
+	return false
+}
+
+// pgnEval writes a score, from white's point of view, as the value of an [%eval]
+// command: pawns with two decimals, or #n for a mate in n moves, negative when black
+// mates.
+func pgnEval(score Score) string {
+	if score > scoreMateBound {
+		return fmt.Sprintf("#%d", (scoreMate-score+1)/2)
+	}
+	if score < -scoreMateBound {
+		return fmt.Sprintf("#-%d", (scoreMate+score+1)/2)
+	}
+	return fmt.Sprintf("%.2f", float64(score)/100)
+}
+
+// replayPGN plays a line of moves from the current position, checking each move and
+// writing it again in SAN the way formatSAN does. The variations of each move are
+// checked from the position before it and taken back. The moves that could be played
+// are returned and left on the board.
+func (g *Game) replayPGN(moves []pgnMove) ([]pgnMove, error) {
+	line := make([]pgnMove, 0, len(moves))
+	for _, pm := range moves {
+		var variations [][]pgnMove
+		for _, v := range pm.variations {
+			checked, err := g.replayPGN(v)
+			for range checked {
+				g.Undo()
+			}
+			if err != nil {
+				return line, err
+			}
+			variations = append(variations, checked)
+		}
+		m, err := g.parseSAN(pm.san)
+		if err != nil {
+			return line, fmt.Errorf("%s %w", g.moveNumber(), err)
+		}
+		pm.san = g.formatSAN(m)
+		pm.variations = variations
+		g.play(m)
+		g.undoStack[len(g.undoStack)-1].notes = pm.notes
+		line = append(line, pm)
+	}
+	return line, nil
+}
+
+// moveNumber returns the number of the move to play as PGN writes it, 12. for white
+// and 12... for black.
+func (g *Game) moveNumber() string {
+	if g.whiteToMove {
+		return strconv.Itoa(g.fullmoveNumber) + "."
+	}
+	return strconv.Itoa(g.fullmoveNumber) + "..."
+}
+
+// checkPGN plays the main line of a game and its variations. It returns the game as
+// far as the main line could be played, with every move written again in SAN, and
+// the first move that could not be played or a result the final position contradicts.
+func checkPGN(game pgnGame) (Game, pgnGame, error) {
+	g := gameFromTags(game.tags)
+	moves, err := g.replayPGN(game.moves)
+	game.moves = moves
+	if err != nil {
+		return g, game, err
+	}
+	if tag, ok := game.tags["Result"]; ok && game.result != "" && tag != game.result {
+		return g, game, fmt.Errorf("the Result tag %s does not match the result %s", tag, game.result)
+	}
+	if status, reason := g.Result(); (reason == Checkmate || reason == Stalemate) && game.result != status.String() {
+		return g, game, fmt.Errorf("result %s, but the game ends by %s (%s)", game.result, reason, status)
+	}
+	return g, game, nil
+}
+
+// pgn returns the game so far as a PGN game, with the engine's settings, its
+// evaluation of each of its moves and the result once the game is over.
+func (g *Game) pgn() pgnGame {
+	start := *g
+	start.redoStack = nil
+	start.rewind()
+	start.history = append([]uint64(nil), start.history...)
+	start.undoStack = nil
+
+	game := pgnGame{tags: map[string]string{
+		"Event":      "ch3ckm8 game",
+		"Site":       "?",
+		"Date":       time.Now().Format("2006.01.02"),
+		"Round":      "-",
+		"White":      "?",
+		"Black":      "?",
+		"Annotator":  "ch3ckm8",
+		"Depth":      strconv.Itoa(searchDepth),
+		"Evaluation": evaluator.Name(),
+		"OwnBook":    strconv.FormatBool(ownBook && openingBook != nil),
+	}}
+	if fen := start.fen(); fen != startPosition {
+		game.tags["SetUp"] = "1"
+		game.tags["FEN"] = fen
+	}
+	for _, u := range g.undoStack {
+		if u.byEngine && start.whiteToMove {
+			game.tags["White"] = "ch3ckm8"
+		} else if u.byEngine {
+			game.tags["Black"] = "ch3ckm8"
+		}
+		game.moves = append(game.moves, pgnMove{san: start.formatSAN(u.move), notes: u.notes})
+		start.play(u.move)
+	}
+	status, reason := g.Result()
+	game.result = status.String()
+	if status != Ongoing {
+		game.tags["Termination"] = reason.String()
+	}
+	return game
+}
+
+// savePGN writes the game to a PGN file.
+func (g *Game) savePGN(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := writePGN(file, g.pgn()); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// loadPGN reads the first game of a PGN file and plays its main line.
+func loadPGN(path string) (Game, error) {
+	games, err := readPGNFile(path)
+	if err != nil {
+		return Game{}, err
+	}
+	if len(games) == 0 {
+		return Game{}, fmt.Errorf("no games in %s", path)
+	}
+	g, _, err := checkPGN(games[0])
+	return g, err
+}
+
+func readPGNFile(path string) ([]pgnGame, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	games, err := readPGN(file)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	return games, nil
+}
+
+// PGNGameReport is what checking one game of a PGN file found.
+type PGNGameReport struct {
+	Game   int // the game's number in the file, from 1
+	White  string
+	Black  string
+	Result string
+	Plies  int   // moves of the main line that could be played
+	Err    error // the first problem found, or nil
+}
+
+// ValidatePGN reads a PGN file and plays every game in it, variations included,
+// reporting the first illegal, ambiguous or unreadable move of each game and results
+// that contradict the final position.
+func ValidatePGN(path string) ([]PGNGameReport, error) {
+	return convertPGN(path, nil, "")
+}
+
+// ConvertPGN writes the games of a PGN file that validate to outPath in one of these
+// formats:
+//
+//	pgn  the games in export format, moves written again in standard SAN
+//	uci  one line per game, the arguments of a position command that sets it up
+//	epd  every position of each finished game's main line with the game's result,
+//	     as tune reads them
+func ConvertPGN(path, outPath, format string) ([]PGNGameReport, error) {
+	if format != "pgn" && format != "uci" && format != "epd" {
+		return nil, fmt.Errorf("unknown format %q, want pgn, uci or epd", format)
+	}
+	file, err := os.Create(outPath)
+	if err != nil {
+		return nil, err
+	}
+	out := bufio.NewWriter(file)
+	reports, err := convertPGN(path, out, format)
+	if err == nil {
+		err = out.Flush()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return reports, err
+}
+
+// convertPGN checks the games of a PGN file and writes those without problems to out,
+// if it is not nil.
+func convertPGN(path string, out io.Writer, format string) ([]PGNGameReport, error) {
+	games, err := readPGNFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var reports []PGNGameReport
+	for n, game := range games {
+		g, checked, err := checkPGN(game)
+		reports = append(reports, PGNGameReport{
+			Game:   n + 1,
+			White:  game.tags["White"],
+			Black:  game.tags["Black"],
+			Result: game.result,
+			Plies:  len(checked.moves),
+			Err:    err,
+		})
+		if err != nil || out == nil {
+			continue
+		}
+		switch format {
+		case "pgn":
+			err = writePGN(out, checked)
+		case "uci":
+			err = writeUCIGame(out, g)
+		case "epd":
+			err = writeEPDGame(out, g, checked.result)
+		}
+		if err != nil {
+			return reports, err
+		}
+	}
+	return reports, nil
+}
+
+// writeUCIGame writes the start of a game and its moves as the arguments of a position
+// command.
+func writeUCIGame(w io.Writer, g Game) error {
+	moves := make([]string, len(g.undoStack))
+	for i, u := range g.undoStack {
+		moves[i] = u.move.String()
+	}
+	g.rewind()
+	setup := "startpos"
+	if fen := g.fen(); fen != startPosition {
+		setup = "fen " + fen
+	}
+	if len(moves) > 0 {
+		setup += " moves " + strings.Join(moves, " ")
+	}
+	_, err := fmt.Fprintln(w, setup)
+	return err
+}
+
+// writeEPDGame writes every position of a game with the game's result, leaving out
+// games without one.
+func writeEPDGame(w io.Writer, g Game, result string) error {
+	if result != "1-0" && result != "0-1" && result != "1/2-1/2" {
+		return nil
+	}
+	var lines []string
+	for {
+		lines = append(lines, fmt.Sprintf("%s c9 \"%s\";", strings.Join(strings.Fields(g.fen())[:4], " "), result))
+		if _, ok := g.Undo(); !ok {
+			break
+		}
+	}
+	for i := len(lines) - 1; i >= 0; i-- {
+		if _, err := fmt.Fprintln(w, lines[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
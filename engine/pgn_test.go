@@ -0,0 +1,259 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const pgnTestGames = `% an escaped line
+[Event "Casual \"blitz\""]
+[Site "?"]
+[White "Anna"]
+[Black "Ben"]
+[Result "1-0"]
+
+{Before the first move} 1. e4 $1 e5 {[%eval 0.30] [%clk 0:05:00] solid} 2. Nf3!?
+(2. f4 exf4 (2... d5) 3. Nf3) 2... Nc6 ; a rest of line comment
+3. Bc4 Nf6?? 4. Ng5 d5 5. exd5 Nxd5 6. Nxf7 1-0
+
+[Event "Second"]
+[White "Cleo"]
+[Black "Dan"]
+[SetUp "1"]
+[FEN "4k3/8/8/8/8/8/4P3/4K3 b - - 0 40"]
+
+40... Kd7 41. e4 *
+`
+
+func writeTestFile(t *testing.T, name, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadPGN(t *testing.T) {
+	games, err := readPGN(strings.NewReader(pgnTestGames))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(games) != 2 {
+		t.Fatalf("read %d games, want 2", len(games))
+	}
+	g := games[0]
+	if g.tags["Event"] != `Casual "blitz"` || g.tags["White"] != "Anna" || g.result != "1-0" {
+		t.Errorf("tags %v and result %s", g.tags, g.result)
+	}
+	var sans []string
+	for _, m := range g.moves {
+		sans = append(sans, m.san)
+	}
+	if got := strings.Join(sans, " "); got != "e4 e5 Nf3 Nc6 Bc4 Nf6 Ng5 d5 exd5 Nxd5 Nxf7" {
+		t.Errorf("main line %s", got)
+	}
+	e4, e5, nf3, nc6, nf6 := g.moves[0], g.moves[1], g.moves[2], g.moves[3], g.moves[5]
+	if e4.before != "Before the first move" || !reflect.DeepEqual(e4.notes.nags, []int{1}) {
+		t.Errorf("1. e4 has comment %q before it and NAGs %v", e4.before, e4.notes.nags)
+	}
+	if n := e5.notes; n.comment != "solid" || n.eval != "0.30" || n.clock != "0:05:00" || len(n.nags) != 0 {
+		t.Errorf("1... e5 has notes %+v", e5.notes)
+	}
+	if !reflect.DeepEqual(nf3.notes.nags, []int{5}) || !reflect.DeepEqual(nf6.notes.nags, []int{4}) {
+		t.Errorf("2. Nf3!? has NAGs %v and 3... Nf6?? %v", nf3.notes.nags, nf6.notes.nags)
+	}
+	if nc6.notes.comment != "a rest of line comment" {
+		t.Errorf("2... Nc6 has comment %q", nc6.notes.comment)
+	}
+	if len(nf3.variations) != 1 || len(nf3.variations[0]) != 3 || nf3.variations[0][0].san != "f4" {
+		t.Fatalf("2. Nf3 has variations %+v", nf3.variations)
+	}
+	if inner := nf3.variations[0][1].variations; len(inner) != 1 || inner[0][0].san != "d5" {
+		t.Errorf("2... exf4 has variations %+v", inner)
+	}
+
+	g = games[1]
+	if g.tags["FEN"] == "" || g.result != "*" || len(g.moves) != 2 || g.moves[0].san != "Kd7" {
+		t.Errorf("second game read as %+v", g)
+	}
+}
+
+func TestWritePGNRoundTrip(t *testing.T) {
+	games, err := readPGN(strings.NewReader(pgnTestGames))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sb strings.Builder
+	for _, g := range games {
+		if err := writePGN(&sb, g); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, line := range strings.Split(sb.String(), "\n") {
+		if len(line) > 80 {
+			t.Errorf("line longer than 80 columns: %s", line)
+		}
+	}
+	if !strings.Contains(sb.String(), "[Date \"????.??.??\"]") || !strings.Contains(sb.String(), "40... Kd7 41. e4 *") {
+		t.Errorf("written games lack the roster or the move numbers:\n%s", sb.String())
+	}
+	again, err := readPGN(strings.NewReader(sb.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range games {
+		// the roster tags are filled in on writing
+		for _, name := range pgnRoster {
+			if _, ok := games[i].tags[name]; !ok {
+				delete(again[i].tags, name)
+			}
+		}
+	}
+	if !reflect.DeepEqual(again, games) {
+		t.Errorf("games read back differ from the games written:\n%s", sb.String())
+	}
+}
+
+func TestPGNEval(t *testing.T) {
+	tests := []struct {
+		score Score
+		want  string
+	}{
+		{35, "0.35"},
+		{-120, "-1.20"},
+		{scoreMate - 1, "#1"},
+		{scoreMate - 3, "#2"},
+		{-(scoreMate - 2), "#-1"},
+	}
+	for _, tt := range tests {
+		if got := pgnEval(tt.score); got != tt.want {
+			t.Errorf("pgnEval(%d) = %s, want %s", tt.score, got, tt.want)
+		}
+	}
+}
+
+func TestValidatePGN(t *testing.T) {
+	path := writeTestFile(t, "games.pgn", pgnTestGames+`
+[White "Illegal"]
+[Result "1-0"]
+
+1. e4 e5 2. Ke3 1-0
+
+[White "Bad variation"]
+[Result "*"]
+
+1. e4 (1. Nf6) e5 *
+
+[White "Wrong result"]
+[Result "1-0"]
+
+1. f3 e5 2. g4 Qh4# 1-0
+
+[White "Mismatched tag"]
+[Result "0-1"]
+
+1. e4 1-0
+`)
+	reports, err := ValidatePGN(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		plies int
+		err   string
+	}{
+		{11, ""},
+		{2, ""},
+		{2, "2. illegal move \"Ke3\""},
+		{0, "no knight can go to f6"},
+		{4, "ends by checkmate"},
+		{1, "does not match"},
+	}
+	if len(reports) != len(want) {
+		t.Fatalf("%d reports, want %d", len(reports), len(want))
+	}
+	for i, r := range reports {
+		if r.Game != i+1 || r.Plies != want[i].plies || (r.Err == nil) != (want[i].err == "") ||
+			(r.Err != nil && !strings.Contains(r.Err.Error(), want[i].err)) {
+			t.Errorf("game %d: report %+v, want %d plies and error %q", i+1, r, want[i].plies, want[i].err)
+		}
+	}
+}
+
+func TestConvertPGN(t *testing.T) {
+	path := writeTestFile(t, "games.pgn", pgnTestGames)
+	out := filepath.Join(t.TempDir(), "out")
+	tests := []struct {
+		format string
+		want   []string
+	}{
+		{"uci", []string{
+			"startpos moves e2e4 e7e5 g1f3 b8c6 f1c4 g8f6 f3g5 d7d5 e4d5 f6d5 g5f7",
+			"fen 4k3/8/8/8/8/8/4P3/4K3 b - - 0 40 moves e8d7 e2e4",
+		}},
+		// only the finished game, one line per position
+		{"epd", []string{
+			`rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - c9 "1-0";`,
+			`rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 c9 "1-0";`,
+		}},
+	}
+	for _, tt := range tests {
+		if _, err := ConvertPGN(path, out, tt.format); err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+		if tt.format == "epd" && len(lines) != 12 {
+			t.Errorf("epd has %d positions, want 12", len(lines))
+		}
+		for i, want := range tt.want {
+			if i >= len(lines) || lines[i] != want {
+				t.Errorf("%s line %d: %q, want %q", tt.format, i+1, lines[min(i, len(lines)-1)], want)
+			}
+		}
+	}
+
+	if _, err := ConvertPGN(path, out, "pgn"); err != nil {
+		t.Fatal(err)
+	}
+	if reports, err := ValidatePGN(out); err != nil || len(reports) != 2 || reports[0].Err != nil || reports[1].Err != nil {
+		t.Errorf("converted pgn does not validate: %+v, %v", reports, err)
+	}
+	if _, err := ConvertPGN(path, out, "csv"); err == nil {
+		t.Error("unknown format accepted")
+	}
+}
+
+func TestSaveAndLoadPGN(t *testing.T) {
+	g := newTestGame(t, "fen 4k3/8/8/8/8/8/4P3/4K3 w - - 0 1 moves e2e4 e8d7 e1e2")
+	g.undoStack[0].byEngine = true
+	g.undoStack[0].notes = pgnNotes{eval: "1.50"}
+	g.Resign(false)
+	path := filepath.Join(t.TempDir(), "game.pgn")
+	if err := g.savePGN(path); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`[White "ch3ckm8"]`, `[Result "1-0"]`, `[Termination "resignation"]`, `[FEN "4k3/8/8/8/8/8/4P3/4K3 w - - 0 1"]`, "1. e4 {[%eval 1.50]} 1... Kd7 2. Ke2 1-0"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("saved game lacks %s:\n%s", want, data)
+		}
+	}
+	loaded, err := loadPGN(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.fen() != g.fen() || len(loaded.undoStack) != 3 || loaded.undoStack[0].notes.eval != "1.50" {
+		t.Errorf("loaded game at %s with %d moves, want %s with 3", loaded.fen(), len(loaded.undoStack), g.fen())
+	}
+}
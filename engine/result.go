@@ -0,0 +1,130 @@
+package engine
+
+// GameStatus tells whether a game is still being played and, if not, who won.
+type GameStatus int
+
+const (
+	Ongoing GameStatus = iota
+	WhiteWins
+	BlackWins
+	Draw
+)
+
+// String returns the status as a PGN result token.
+func (s GameStatus) String() string {
+	switch s {
+	case WhiteWins:
+		return "1-0"
+	case BlackWins:
+		return "0-1"
+	case Draw:
+		return "1/2-1/2"
+	}
+	return "*"
+}
+
+// ResultReason tells how a finished game ended.
+type ResultReason int
+
+const (
+	NoReason ResultReason = iota
+	Checkmate
+	Stalemate
+	Repetition
+	FiftyMove
+	InsufficientMaterial
+	Resignation
+	Timeout
+)
+
+func (r ResultReason) String() string {
+	switch r {
+	case Checkmate:
+		return "checkmate"
+	case Stalemate:
+		return "stalemate"
+	case Repetition:
+		return "threefold repetition"
+	case FiftyMove:
+		return "fifty-move rule"
+	case InsufficientMaterial:
+		return "insufficient material"
+	case Resignation:
+		return "resignation"
+	case Timeout:
+		return "timeout"
+	}
+	return ""
+}
+
+// Result returns the state of the game and, once it is over, the reason it ended.
+// Results decided off the board, by resignation or timeout, take precedence.
+func (g *Game) Result() (GameStatus, ResultReason) {
+	if g.status != Ongoing {
+		return g.status, g.reason
+	}
+	b := &g.board
+	if !b.hasLegalMove(g.whiteToMove) {
+		if !b.isCheck(g.whiteToMove) {
+			return Draw, Stalemate
+		}
+		if g.whiteToMove {
+			return BlackWins, Checkmate
+		}
+		return WhiteWins, Checkmate
+	}
+	if b.hasInsufficientMaterial() {
+		return Draw, InsufficientMaterial
+	}
+	if g.isThreefoldRepetition() {
+		return Draw, Repetition
+	}
+	if g.isFiftyMoveDraw() {
+		return Draw, FiftyMove
+	}
+	return Ongoing, NoReason
+}
+
+// IsOver reports whether the game has ended.
+func (g *Game) IsOver() bool {
+	status, _ := g.Result()
+	return status != Ongoing
+}
+
+// Resign ends the game with the given side resigning.
+func (g *Game) Resign(isWhite bool) {
+	g.status = winnerStatus(!isWhite)
+	g.reason = Resignation
+}
+
+// Timeout ends the game with the given side having run out of time. The game is
+// drawn instead if the opponent has no material left to mate with.
+func (g *Game) Timeout(isWhite bool) {
+	g.reason = Timeout
+	if !g.board.hasMatingMaterial(!isWhite) {
+		g.status = Draw
+		return
+	}
+	g.status = winnerStatus(!isWhite)
+}
+
+// resultMessage describes how the game ended, or returns "" while it is ongoing.
+func (g *Game) resultMessage() string {
+	status, reason := g.Result()
+	switch status {
+	case WhiteWins:
+		return "White wins by " + reason.String() + " (" + status.String() + ")"
+	case BlackWins:
+		return "Black wins by " + reason.String() + " (" + status.String() + ")"
+	case Draw:
+		return "Draw by " + reason.String() + " (" + status.String() + ")"
+	}
+	return ""
+}
+
+func winnerStatus(isWhite bool) GameStatus {
+	if isWhite {
+		return WhiteWins
+	}
+	return BlackWins
+}
@@ -0,0 +1,53 @@
+package engine
+
+import "testing"
+
+func TestResult(t *testing.T) {
+	tests := []struct {
+		name     string
+		position string
+		status   GameStatus
+		reason   ResultReason
+	}{
+		{"start", "startpos", Ongoing, NoReason},
+		{"fool's mate", "startpos moves f2f3 e7e5 g2g4 d8h4", BlackWins, Checkmate},
+		{"back rank mate", "fen 6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1 moves a1a8", WhiteWins, Checkmate},
+		{"stalemate", "fen 7k/5Q2/6K1/8/8/8/8/8 b - - 0 1", Draw, Stalemate},
+		{"bare kings", "fen 8/8/4k3/8/8/3K4/8/8 w - - 0 1", Draw, InsufficientMaterial},
+		{"lone knight", "fen 8/8/4k3/8/8/3KN3/8/8 w - - 0 1", Draw, InsufficientMaterial},
+		{"bishops on one colour", "fen 8/8/1b2k3/8/8/3K4/8/B7 w - - 0 1", Draw, InsufficientMaterial},
+		{"bishops on both colours", "fen 8/8/2b1k3/8/8/3K4/8/B7 w - - 0 1", Ongoing, NoReason},
+		{"knight against bishop", "fen 8/8/1b2k3/8/8/3KN3/8/8 w - - 0 1", Ongoing, NoReason},
+		{"lone pawn", "fen 8/8/4k3/8/8/3K4/3P4/8 w - - 0 1", Ongoing, NoReason},
+	}
+	for _, tt := range tests {
+		g := newTestGame(t, tt.position)
+		if status, reason := g.Result(); status != tt.status || reason != tt.reason {
+			t.Errorf("%s: Result() = %v, %v, want %v, %v", tt.name, status, reason, tt.status, tt.reason)
+		}
+		if g.IsOver() != (tt.status != Ongoing) {
+			t.Errorf("%s: IsOver() = %v", tt.name, g.IsOver())
+		}
+	}
+}
+
+func TestResignAndTimeout(t *testing.T) {
+	g := newTestGame(t, "startpos")
+	g.Resign(true)
+	if status, reason := g.Result(); status != BlackWins || reason != Resignation {
+		t.Errorf("after white resigns Result() = %v, %v", status, reason)
+	}
+
+	g = newTestGame(t, "startpos")
+	g.Timeout(false)
+	if status, reason := g.Result(); status != WhiteWins || reason != Timeout {
+		t.Errorf("after black times out Result() = %v, %v", status, reason)
+	}
+
+	// black cannot mate with a lone knight, so white running out of time draws
+	g = newTestGame(t, "fen 8/8/4k3/8/8/3KQ3/8/7n w - - 0 1")
+	g.Timeout(true)
+	if status, reason := g.Result(); status != Draw || reason != Timeout {
+		t.Errorf("timeout against a lone knight: Result() = %v, %v, want a draw", status, reason)
+	}
+}
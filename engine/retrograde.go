@@ -0,0 +1,486 @@
+package engine
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// A retrograde table stores, for every placement of its pieces and either side to
+// move, how many plies it takes to mate with best play. It is worked out backwards
+// from the mates: a position is won in n+1 plies if a move leads to a position lost in
+// n, and lost in n+1 plies once every move leads to a position won for the opponent.
+// Whatever is never reached that way is a draw. The fifty-move rule is ignored.
+//
+// Only pawnless material with up to four pieces, kings included, is supported.
+// Captures leave the table for a smaller one, which is generated first.
+
+const (
+	maxRetroPieces = 4
+	retroMagic     = "CKTB"
+	retroVersion   = 1
+	maxRetroPlies  = 254 // distances are stored in a byte, with 0 for a draw
+)
+
+// tbPiece is the piece on one square of a table index.
+type tbPiece struct {
+	pieceType PieceType
+	isWhite   bool
+}
+
+// RetroTable is the distance to mate table for one material, e.g. KRvK.
+type RetroTable struct {
+	material string
+	pieces   []tbPiece // the piece whose square goes in each 6 bits of the index, white's first
+	entries  []uint8   // by index: 0 for a draw, otherwise plies to mate plus one
+}
+
+// RetroTables is a set of retrograde tables by material. It implements Tablebase.
+type RetroTables map[string]*RetroTable
+
+// materialOrder is the order pieces are listed in within a material name.
+const materialOrder = "KQRBN"
+
+// parseMaterial checks a material name like KRvK and returns it with each side's
+// pieces in the usual order, together with the pieces of the table index.
+func parseMaterial(material string) (string, []tbPiece, error) {
+	white, black, ok := strings.Cut(strings.ToUpper(material), "V")
+	if !ok {
+		return "", nil, fmt.Errorf("material %s: expected the pieces of each side separated by v, e.g. KRvK", material)
+	}
+	var pieces []tbPiece
+	var sides []string
+	for _, side := range []struct {
+		pieces  string
+		isWhite bool
+	}{{white, true}, {black, false}} {
+		if strings.Count(side.pieces, "K") != 1 {
+			return "", nil, fmt.Errorf("material %s: each side needs one king", material)
+		}
+		if strings.Contains(side.pieces, "P") {
+			return "", nil, fmt.Errorf("material %s: pawns are not supported", material)
+		}
+		if strings.Trim(side.pieces, materialOrder) != "" {
+			return "", nil, fmt.Errorf("material %s: unknown piece in %s", material, side.pieces)
+		}
+		sorted := []byte(side.pieces)
+		sort.Slice(sorted, func(i, j int) bool {
+			return strings.IndexByte(materialOrder, sorted[i]) < strings.IndexByte(materialOrder, sorted[j])
+		})
+		for _, c := range sorted {
+			pieces = append(pieces, tbPiece{PieceType(c), side.isWhite})
+		}
+		sides = append(sides, string(sorted))
+	}
+	if len(pieces) > maxRetroPieces {
+		return "", nil, fmt.Errorf("material %s: at most %d pieces are supported", material, maxRetroPieces)
+	}
+	return sides[0] + "v" + sides[1], pieces, nil
+}
+
+// materialName names the material of a list of pieces, white's first.
+func materialName(pieces []tbPiece) string {
+	var white, black strings.Builder
+	for _, p := range pieces {
+		if p.isWhite {
+			white.WriteRune(rune(p.pieceType))
+		} else {
+			black.WriteRune(rune(p.pieceType))
+		}
+	}
+	return white.String() + "v" + black.String()
+}
+
+// GenerateRetroTable works out the table for a material, printing a line for it and
+// for each smaller table its captures lead to.
+func GenerateRetroTable(material string) (*RetroTable, error) {
+	tables := RetroTables{}
+	if err := tables.generate(material); err != nil {
+		return nil, err
+	}
+	name, _, _ := parseMaterial(material)
+	return tables[name], nil
+}
+
+// generate adds the table for a material to the set, after the tables for every
+// material a capture leads to.
+func (tables RetroTables) generate(material string) error {
+	name, pieces, err := parseMaterial(material)
+	if err != nil {
+		return err
+	}
+	if _, ok := tables[name]; ok {
+		return nil
+	}
+	for i, p := range pieces {
+		if p.pieceType == King {
+			continue
+		}
+		rest := append(append([]tbPiece{}, pieces[:i]...), pieces[i+1:]...)
+		if err := tables.generate(materialName(rest)); err != nil {
+			return err
+		}
+	}
+
+	t := &RetroTable{material: name, pieces: pieces, entries: make([]uint8, 2<<(6*len(pieces)))}
+	g := retroGenerator{
+		t:      t,
+		tables: tables,
+		state:  make([]uint8, len(t.entries)),
+		moves:  make([]uint8, len(t.entries)),
+		lossAt: make([]uint8, len(t.entries)),
+	}
+	if err := g.run(); err != nil {
+		return err
+	}
+	tables[name] = t
+
+	wins, losses, draws, longest := t.stats()
+	fmt.Printf("%s: %d won, %d lost, %d drawn positions, longest mate %d plies\n", name, wins, losses, draws, longest)
+	return nil
+}
+
+const (
+	retroInvalid uint8 = iota // the side not to move is in check, or two pieces share a square
+	retroUnknown
+	retroDone
+)
+
+// retroGenerator holds the working state while a table is generated.
+type retroGenerator struct {
+	t       *RetroTable
+	tables  RetroTables
+	state   []uint8
+	moves   []uint8   // legal moves not yet known to lose
+	lossAt  []uint8   // plies to mate after the slowest losing capture
+	pending [][]int32 // positions to settle, by plies to mate
+	err     error
+}
+
+func (g *retroGenerator) run() error {
+	var b Board
+	for idx := range g.t.entries {
+		if whiteToMove, ok := g.t.setup(&b, idx); ok {
+			g.state[idx] = retroUnknown
+			g.initial(&b, idx, whiteToMove)
+		}
+	}
+	for plies := 0; plies < len(g.pending) && g.err == nil; plies++ {
+		for _, idx := range g.pending[plies] {
+			if g.state[idx] != retroUnknown {
+				continue
+			}
+			g.state[idx] = retroDone
+			g.t.entries[idx] = uint8(plies + 1)
+			g.unmoves(&b, int(idx), plies)
+		}
+	}
+	return g.err
+}
+
+// schedule settles a position as mate in the given plies, unless it is settled sooner.
+func (g *retroGenerator) schedule(idx, plies int) {
+	if plies > maxRetroPlies {
+		g.err = fmt.Errorf("%s: mate takes more than %d plies", g.t.material, maxRetroPlies)
+		return
+	}
+	for len(g.pending) <= plies {
+		g.pending = append(g.pending, nil)
+	}
+	g.pending[plies] = append(g.pending[plies], int32(idx))
+}
+
+// initial counts the legal moves of a position and settles what its captures and
+// the lack of moves decide. A capture that wins or draws counts as a move that never
+// loses, so the position can no longer be lost.
+func (g *retroGenerator) initial(b *Board, idx int, whiteToMove bool) {
+	legal := 0
+	bestWin := -1
+	for i, p := range g.t.pieces {
+		if p.isWhite != whiteToMove {
+			continue
+		}
+		from := uint64(1) << g.t.square(idx, i)
+		for dests := tbAttacks(p.pieceType, from, b.allPieces) &^ b.ownPieces(whiteToMove); dests != 0; dests &= dests - 1 {
+			to := dests & -dests
+			wasPieceCaptured, capturedPieceType := b.makeMove(from, to, whiteToMove, p.pieceType)
+			if !b.isAttacked(b.pieceBitboard(King, whiteToMove), !whiteToMove) {
+				legal++
+				if !wasPieceCaptured {
+					g.moves[idx]++
+				} else if wdl, plies, ok := g.tables.probeDTM(b, !whiteToMove); !ok {
+					g.err = fmt.Errorf("%s: no table for %sv%s after a capture", g.t.material, b.materialString(true), b.materialString(false))
+				} else if wdl == WDLLoss {
+					if bestWin < 0 || plies+1 < bestWin {
+						bestWin = plies + 1
+					}
+					g.moves[idx]++
+				} else if wdl == WDLWin {
+					g.lossAt[idx] = uint8(max(int(g.lossAt[idx]), plies+1))
+				} else {
+					g.moves[idx]++
+				}
+			}
+			b.unmakeMove(from, to, whiteToMove, wasPieceCaptured, capturedPieceType)
+		}
+	}
+
+	switch {
+	case legal == 0 && b.isAttacked(b.pieceBitboard(King, whiteToMove), !whiteToMove):
+		g.schedule(idx, 0)
+	case legal == 0:
+		g.state[idx] = retroDone // stalemate
+	case bestWin >= 0:
+		g.schedule(idx, bestWin)
+	case g.moves[idx] == 0:
+		g.schedule(idx, int(g.lossAt[idx]))
+	}
+}
+
+// unmoves goes back from a settled position to the positions one move before it.
+// If the position is lost, they are won one ply later. If it is won, that move loses
+// for them, and once all their moves lose they are lost as late as their slowest move.
+func (g *retroGenerator) unmoves(b *Board, idx, plies int) {
+	whiteToMove, _ := g.t.setup(b, idx)
+	n := len(g.t.pieces)
+	for i, p := range g.t.pieces {
+		if p.isWhite == whiteToMove {
+			continue
+		}
+		from := uint64(1) << g.t.square(idx, i)
+		for dests := tbAttacks(p.pieceType, from, b.allPieces) &^ b.allPieces; dests != 0; dests &= dests - 1 {
+			sq := bits.TrailingZeros64(dests)
+			prev := idx&^(63<<(6*i)) | sq<<(6*i)
+			prev ^= 1 << (6 * n)
+			if g.state[prev] != retroUnknown {
+				continue
+			}
+			if plies%2 == 0 {
+				g.schedule(prev, plies+1)
+			} else if g.moves[prev]--; g.moves[prev] == 0 {
+				g.schedule(prev, max(plies+1, int(g.lossAt[prev])))
+			}
+		}
+	}
+}
+
+// tbAttacks returns the squares a piece other than a pawn attacks from pos.
+func tbAttacks(pieceType PieceType, pos, occupied uint64) uint64 {
+	switch pieceType {
+	case Knight:
+		return knightAttacks(pos)
+	case Bishop:
+		return slidingAttacks(pos, occupied, diagDirs)
+	case Rook:
+		return slidingAttacks(pos, occupied, straightDirs)
+	case Queen:
+		return slidingAttacks(pos, occupied, allDirs)
+	case King:
+		return kingAttacks(pos)
+	}
+	return 0
+}
+
+// square returns the square of the i-th piece of a table index.
+func (t *RetroTable) square(idx, i int) int {
+	return idx >> (6 * i) & 63
+}
+
+// setup places the pieces of a table index on the board and returns the side to move.
+// ok is false when the index is not a legal position.
+func (t *RetroTable) setup(b *Board, idx int) (whiteToMove, ok bool) {
+	b.Empty()
+	for i, p := range t.pieces {
+		pos := uint64(1) << t.square(idx, i)
+		if b.allPieces&pos != 0 {
+			return false, false
+		}
+		b.movePiece(0, pos, p.pieceType, p.isWhite)
+	}
+	whiteToMove = idx>>(6*len(t.pieces)) == 0
+	return whiteToMove, !b.isAttacked(b.pieceBitboard(King, !whiteToMove), whiteToMove)
+}
+
+// index finds the board in the table. With flip the colours are swapped and the board
+// mirrored, to probe a table with the sides the other way round.
+func (t *RetroTable) index(b *Board, whiteToMove, flip bool) int {
+	var used uint64
+	idx := 0
+	for i, p := range t.pieces {
+		pieces := b.pieceBitboard(p.pieceType, p.isWhite != flip) &^ used
+		pos := pieces & -pieces
+		used |= pos
+		sq := bits.TrailingZeros64(pos)
+		if flip {
+			sq ^= 56
+		}
+		idx |= sq << (6 * i)
+	}
+	if whiteToMove == flip {
+		idx |= 1 << (6 * len(t.pieces))
+	}
+	return idx
+}
+
+// stats counts the positions won and lost for the side to move, and the drawn ones,
+// which include the positions that cannot occur.
+func (t *RetroTable) stats() (wins, losses, draws, longest int) {
+	for _, e := range t.entries {
+		switch {
+		case e == 0:
+			draws++
+		case (e-1)%2 == 1:
+			wins++
+		default:
+			losses++
+		}
+		longest = max(longest, int(e)-1)
+	}
+	return wins, losses, draws, longest
+}
+
+// Material returns the name of the table's material.
+func (t *RetroTable) Material() string {
+	return t.material
+}
+
+// Save writes the table to a file.
+func (t *RetroTable) Save(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(file)
+	w.WriteString(retroMagic)
+	binary.Write(w, binary.LittleEndian, uint32(retroVersion))
+	w.WriteByte(byte(len(t.material)))
+	w.WriteString(t.material)
+	w.Write(t.entries)
+	if err := w.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// LoadRetroTable reads a table written by Save.
+func LoadRetroTable(path string) (*RetroTable, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	r := bufio.NewReader(file)
+	var header struct {
+		Magic   [4]byte
+		Version uint32
+		Length  uint8
+	}
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return nil, fmt.Errorf("reading table %s: %w", path, err)
+	}
+	if string(header.Magic[:]) != retroMagic {
+		return nil, fmt.Errorf("reading table %s: not a table file", path)
+	}
+	if header.Version != retroVersion {
+		return nil, fmt.Errorf("reading table %s: unsupported version %d", path, header.Version)
+	}
+	material := make([]byte, header.Length)
+	if _, err := io.ReadFull(r, material); err != nil {
+		return nil, fmt.Errorf("reading table %s: %w", path, err)
+	}
+	name, pieces, err := parseMaterial(string(material))
+	if err != nil {
+		return nil, fmt.Errorf("reading table %s: %w", path, err)
+	}
+	t := &RetroTable{material: name, pieces: pieces, entries: make([]uint8, 2<<(6*len(pieces)))}
+	if _, err := io.ReadFull(r, t.entries); err != nil {
+		return nil, fmt.Errorf("reading table %s: %w", path, err)
+	}
+	if _, err := r.ReadByte(); !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("reading table %s: unexpected data after the entries", path)
+	}
+	return t, nil
+}
+
+// LoadRetroTables reads the table files in a list separated as in PATH.
+func LoadRetroTables(paths string) (RetroTables, error) {
+	tables := RetroTables{}
+	for _, path := range filepath.SplitList(paths) {
+		t, err := LoadRetroTable(path)
+		if err != nil {
+			return nil, err
+		}
+		tables[t.material] = t
+	}
+	return tables, nil
+}
+
+func (tables RetroTables) MaxPieces() int {
+	most := 0
+	for _, t := range tables {
+		most = max(most, len(t.pieces))
+	}
+	return most
+}
+
+// probeDTM looks the board up and returns the result for the side to move and the
+// plies to mate, 0 for a draw or when the side to move is mated.
+func (tables RetroTables) probeDTM(b *Board, whiteToMove bool) (WDL, int, bool) {
+	white, black := b.materialString(true), b.materialString(false)
+	var idx int
+	t, ok := tables[white+"v"+black]
+	if ok {
+		idx = t.index(b, whiteToMove, false)
+	} else if t, ok = tables[black+"v"+white]; ok {
+		idx = t.index(b, whiteToMove, true)
+	} else {
+		return WDLDraw, 0, false
+	}
+	e := int(t.entries[idx])
+	switch {
+	case e == 0:
+		return WDLDraw, 0, true
+	case (e-1)%2 == 1:
+		return WDLWin, e - 1, true
+	}
+	return WDLLoss, e - 1, true
+}
+
+func (tables RetroTables) ProbeWDL(b *Board, whiteToMove bool) (WDL, bool) {
+	wdl, _, ok := tables.probeDTM(b, whiteToMove)
+	return wdl, ok
+}
+
+// ProbeDTZ returns the distance to mate: with no pawns and no captures within a
+// table, mate is the only thing that ends the count.
+func (tables RetroTables) ProbeDTZ(b *Board, whiteToMove bool) (int, bool) {
+	wdl, plies, ok := tables.probeDTM(b, whiteToMove)
+	if wdl == WDLLoss {
+		plies = -plies
+	}
+	return plies, ok
+}
+
+// ProbeRetroTables looks a position up in the given table files. It returns the
+// result for the side to move and the plies to mate.
+func ProbeRetroTables(paths, fen string) (WDL, int, error) {
+	tables, err := LoadRetroTables(paths)
+	if err != nil {
+		return WDLDraw, 0, err
+	}
+	g := parseGame(fen)
+	wdl, plies, ok := tables.probeDTM(&g.board, g.whiteToMove)
+	if !ok {
+		return WDLDraw, 0, fmt.Errorf("no table for %sv%s", g.board.materialString(true), g.board.materialString(false))
+	}
+	return wdl, plies, nil
+}
@@ -0,0 +1,152 @@
+package engine
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func generateTestTables(t *testing.T, material string) RetroTables {
+	t.Helper()
+	tables := RetroTables{}
+	if err := tables.generate(material); err != nil {
+		t.Fatal(err)
+	}
+	return tables
+}
+
+func TestParseMaterial(t *testing.T) {
+	tests := []struct {
+		material, want string
+		ok             bool
+	}{
+		{"KRvK", "KRvK", true},
+		{"krvk", "KRvK", true},
+		{"KNBvK", "KBNvK", true},
+		{"KvKQ", "KvKQ", true},
+		{"KRK", "", false},
+		{"KPvK", "", false},
+		{"KQRvKR", "", false},
+		{"RvK", "", false},
+	}
+	for _, tt := range tests {
+		name, _, err := parseMaterial(tt.material)
+		if (err == nil) != tt.ok || name != tt.want {
+			t.Errorf("parseMaterial(%q) = %q, %v; want %q", tt.material, name, err, tt.want)
+		}
+	}
+}
+
+func TestRetroTableLongestMate(t *testing.T) {
+	// the longest mates with a queen and with a rook take 10 and 16 moves
+	tests := []struct {
+		material string
+		longest  int
+	}{
+		{"KQvK", 2*10 - 1},
+		{"KRvK", 2*16 - 1},
+	}
+	for _, tt := range tests {
+		tables := generateTestTables(t, tt.material)
+		_, _, _, longest := tables[tt.material].stats()
+		// the longest entry is for the losing side, one ply before the winner moves
+		if longest != tt.longest+1 {
+			t.Errorf("%s: longest mate %d plies, want %d", tt.material, longest-1, tt.longest)
+		}
+	}
+}
+
+func TestRetroTableProbe(t *testing.T) {
+	tables := generateTestTables(t, "KRvK")
+	tests := []struct {
+		fen   string
+		wdl   WDL
+		plies int
+	}{
+		{"k7/8/1K6/8/8/8/8/7R w - - 0 1", WDLWin, 1},
+		{"R6k/8/6K1/8/8/8/8/8 b - - 0 1", WDLLoss, 0},
+		{"8/8/8/8/5k2/6R1/8/7K b - - 0 1", WDLDraw, 0},
+		{"8/8/8/8/8/1K6/8/k6R b - - 0 1", WDLLoss, 0},
+		{"7k/8/6K1/8/8/8/8/R7 w - - 0 1", WDLWin, 1},
+		// the same with the colours swapped
+		{"8/8/8/8/8/6k1/r7/7K b - - 0 1", WDLWin, 1},
+		{"8/8/8/8/6k1/8/8/K7 w - - 0 1", WDLDraw, 0},
+	}
+	for _, tt := range tests {
+		g := parseGame(tt.fen)
+		wdl, plies, ok := tables.probeDTM(&g.board, g.whiteToMove)
+		if !ok || wdl != tt.wdl || plies != tt.plies {
+			t.Errorf("%s: probe gave %v %d %v, want %v %d", tt.fen, wdl, plies, ok, tt.wdl, tt.plies)
+		}
+	}
+}
+
+// TestRetroTableIsConsistent checks a sample of positions against the moves from them:
+// a position won in n plies has a move to one lost in n-1 and none to one lost sooner,
+// and in a position lost in n plies every move leads to one won in at most n-1.
+func TestRetroTableIsConsistent(t *testing.T) {
+	tables := generateTestTables(t, "KRvK")
+	table := tables["KRvK"]
+	var b Board
+	for idx := 0; idx < len(table.entries); idx += 101 {
+		whiteToMove, ok := table.setup(&b, idx)
+		if !ok {
+			continue
+		}
+		wdl, plies, _ := tables.probeDTM(&b, whiteToMove)
+		var moves MoveList
+		b.generateMoves(whiteToMove, &moves)
+		best := -1 // plies to mate after the best move, for the side that made it
+		for _, m := range moves.slice() {
+			undo := b.doMove(m, whiteToMove)
+			reply, replyPlies, ok := tables.probeDTM(&b, !whiteToMove)
+			b.undoMove(m, whiteToMove, undo)
+			if !ok {
+				t.Fatalf("index %d: position after %v not found", idx, m)
+			}
+			switch {
+			case wdl == WDLWin && reply == WDLLoss:
+				if best == -1 || replyPlies < best {
+					best = replyPlies
+				}
+			case wdl == WDLLoss && reply != WDLWin:
+				t.Fatalf("index %d: %v escapes a lost position", idx, m)
+			case wdl == WDLLoss:
+				best = max(best, replyPlies)
+			case wdl == WDLDraw && reply == WDLLoss:
+				t.Fatalf("index %d: %v wins a drawn position", idx, m)
+			}
+		}
+		if wdl != WDLDraw && moves.len() > 0 && best+1 != plies {
+			t.Fatalf("index %d: %v in %d plies, but the best move leads to %d", idx, wdl, plies, best)
+		}
+	}
+}
+
+func TestRetroTableSaveAndLoad(t *testing.T) {
+	tables := generateTestTables(t, "KQvK")
+	dir := t.TempDir()
+	var paths []string
+	for name, table := range tables {
+		path := filepath.Join(dir, name+".tb")
+		if err := table.Save(path); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+	loaded, err := LoadRetroTables(paths[0] + string(filepath.ListSeparator) + paths[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, table := range tables {
+		if got := loaded[name]; got == nil || string(got.entries) != string(table.entries) {
+			t.Errorf("%s does not read back as saved", name)
+		}
+	}
+	wdl, plies, err := ProbeRetroTables(paths[0]+string(filepath.ListSeparator)+paths[1], "k7/8/1K6/8/8/8/7Q/8 w - - 0 1")
+	if err != nil || wdl != WDLWin || plies != 1 {
+		t.Errorf("probe gave %v %d %v, want a win in 1 ply", wdl, plies, err)
+	}
+	if _, _, err := ProbeRetroTables(paths[0], startPosition); err == nil {
+		t.Error("start position found in the tables")
+	}
+}
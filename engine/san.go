@@ -0,0 +1,251 @@
+package engine
+
+import (
+	"fmt"
+	"math/bits"
+	"strings"
+)
+
+// pieceBitboard returns the bitboard of one side's pieces of a type.
+func (b *Board) pieceBitboard(pieceType PieceType, isWhite bool) uint64 {
+	idx := pieceIndex(pieceType)
+	if idx < 0 {
+		return 0
+	}
+	return b.pieces[colourIndex(isWhite)][idx]
+}
+
+// kindBitboard returns the pieces of a kind, given by index, of both sides.
+func (b *Board) kindBitboard(idx int) uint64 {
+	return b.pieces[whiteIndex][idx] | b.pieces[blackIndex][idx]
+}
+
+// isAttacked reports whether a side attacks the square pos.
+func (b *Board) isAttacked(pos uint64, byWhite bool) bool {
+	return b.attackersTo(pos, b.allPieces)&b.colours[colourIndex(byWhite)] != 0
+}
+
+// parseSAN finds the move a move in standard algebraic notation (e.g. Nbd7, exd5,
+// e8=Q+ or O-O) stands for in the current position.
+func (g *Game) parseSAN(san string) (Move, error) {
+	b := &g.board
+	isWhite := g.whiteToMove
+	move := strings.TrimRight(san, "+#!?")
+	promotion := NoPiece
+
+	if move == "O-O" || move == "0-0" || move == "O-O-O" || move == "0-0-0" {
+		return g.parseCastling(san, len(move) == 5)
+	}
+
+	pieceType := Pawn
+	if len(move) > 0 && strings.IndexByte("NBRQK", move[0]) >= 0 {
+		pieceType = PieceType(move[0])
+		move = move[1:]
+	}
+	if i := strings.IndexByte(move, '='); i >= 0 && i+1 < len(move) {
+		promotion = PieceType(move[i+1])
+		move = move[:i]
+	} else if n := len(move); pieceType == Pawn && n > 0 && strings.IndexByte("NBRQ", move[n-1]) >= 0 {
+		promotion = PieceType(move[n-1]) // e8Q
+		move = move[:n-1]
+	}
+	if promotion != NoPiece && strings.IndexByte("NBRQ", byte(promotion)) < 0 {
+		return noMove, fmt.Errorf("invalid move %q: a pawn promotes to N, B, R or Q", san)
+	}
+	// a capture is marked by an x right before the square taken on
+	capture := false
+	if n := len(move); n >= 3 && move[n-3] == 'x' {
+		capture = true
+		move = move[:n-3] + move[n-2:]
+	}
+	if len(move) < 2 || !isSquare(move[len(move)-2:]) {
+		return noMove, fmt.Errorf("invalid move %q", san)
+	}
+	finalPos := b.notationToPos(move[len(move)-2:])
+	disambiguation := move[:len(move)-2]
+	if strings.Trim(disambiguation, "abcdefgh12345678") != "" {
+		return noMove, fmt.Errorf("invalid move %q", san)
+	}
+	if pieceType == Pawn && disambiguation != "" {
+		capture = true // exd5, or ed5 without the x
+	}
+
+	if finalPos&b.colours[colourIndex(isWhite)] != 0 {
+		return noMove, fmt.Errorf("illegal move %q: your own %s stands on %s", san, pieceName(b.getPieceType(finalPos)), uciSquare(finalPos))
+	}
+	enemy := b.colours[colourIndex(!isWhite)]
+	if capture && finalPos&enemy == 0 && !(pieceType == Pawn && finalPos == b.epSquare) {
+		return noMove, fmt.Errorf("illegal move %q: there is nothing to take on %s", san, uciSquare(finalPos))
+	}
+	if pieceType == Pawn && !capture && finalPos&enemy != 0 {
+		return noMove, fmt.Errorf("illegal move %q: a pawn takes diagonally, not straight ahead", san)
+	}
+
+	own := b.pieceBitboard(pieceType, isWhite)
+	var candidates uint64
+	switch pieceType {
+	case Pawn:
+		if disambiguation == "" {
+			// a push, one or two squares
+			behind := finalPos >> 8
+			if !isWhite {
+				behind = finalPos << 8
+			}
+			if behind&own != 0 {
+				candidates = behind
+			} else if behind&b.allPieces == 0 && relativeRank(bits.TrailingZeros64(finalPos), isWhite) == 3 {
+				if isWhite {
+					candidates = behind >> 8 & own
+				} else {
+					candidates = behind << 8 & own
+				}
+			}
+		} else {
+			candidates = pawnAttacks(finalPos, !isWhite) & own
+		}
+	case Knight:
+		candidates = knightAttacks(finalPos) & own
+	case Bishop:
+		candidates = slidingAttacks(finalPos, b.allPieces, diagDirs) & own
+	case Rook:
+		candidates = slidingAttacks(finalPos, b.allPieces, straightDirs) & own
+	case Queen:
+		candidates = slidingAttacks(finalPos, b.allPieces, allDirs) & own
+	case King:
+		candidates = kingAttacks(finalPos) & own
+	}
+	for _, c := range disambiguation {
+		switch {
+		case c >= 'a' && c <= 'h':
+			candidates &= fileMasks[c-'a']
+		case c >= '1' && c <= '8':
+			candidates &= bottomEdge << (8 * uint(c-'1'))
+		}
+	}
+
+	if candidates == 0 {
+		return noMove, fmt.Errorf("illegal move %q: no %s can go to %s", san, pieceName(pieceType), uciSquare(finalPos))
+	}
+	if pieceType == Pawn && finalPos&(bottomEdge|topEdge) != 0 && promotion == NoPiece {
+		return noMove, fmt.Errorf("illegal move %q: the pawn must promote, e.g. %s=Q", san, strings.TrimRight(san, "+#!?"))
+	}
+
+	// drop moves that would leave our own king in check
+	var legal []Move
+	for ; candidates != 0; candidates &= candidates - 1 {
+		if m, ok := g.findMove(candidates&-candidates, finalPos, promotion); ok {
+			legal = append(legal, m)
+		}
+	}
+	switch len(legal) {
+	case 0:
+		if promotion != NoPiece && (pieceType != Pawn || finalPos&(bottomEdge|topEdge) == 0) {
+			return noMove, fmt.Errorf("illegal move %q: only a pawn reaching the last rank promotes", san)
+		}
+		return noMove, fmt.Errorf("illegal move %q: it leaves the king in check", san)
+	case 1:
+		return legal[0], nil
+	}
+	options := make([]string, len(legal))
+	for i, m := range legal {
+		options[i] = g.formatSAN(m)
+	}
+	return noMove, fmt.Errorf("ambiguous move %q: could be %s", san, strings.Join(options, " or "))
+}
+
+// parseCastling finds the castling move of the side to move, if it may castle.
+func (g *Game) parseCastling(san string, long bool) (Move, error) {
+	var moves MoveList
+	g.board.generateMoves(g.whiteToMove, &moves)
+	for _, m := range moves.slice() {
+		if m.isCastle() && (m.to() > m.from()) == long {
+			return m, nil
+		}
+	}
+	return noMove, fmt.Errorf("illegal move %q: castling is not possible", san)
+}
+
+// formatSAN writes a legal move of the side to move in standard algebraic notation.
+// The file or rank the piece comes from is added when another piece of the same kind
+// could go to the same square, and + or # when the move gives check or mate.
+func (g *Game) formatSAN(m Move) string {
+	b := &g.board
+	isWhite := g.whiteToMove
+	from, to := m.from(), m.to()
+	pieceType := b.getPieceType(from)
+	var san string
+	switch {
+	case m.isCastle() && to > from:
+		san = "O-O-O"
+	case m.isCastle():
+		san = "O-O"
+	case pieceType == Pawn:
+		if m.isCapture() {
+			san = uciSquare(from)[:1] + "x"
+		}
+		san += uciSquare(to)
+		if promotion := m.promotion(); promotion != NoPiece {
+			san += "=" + string(rune(promotion))
+		}
+	default:
+		var moves MoveList
+		b.generateMoves(isWhite, &moves)
+		var others uint64
+		for _, other := range moves.slice() {
+			if other.to() == to && other.from() != from && b.getPieceType(other.from()) == pieceType {
+				others |= other.from()
+			}
+		}
+		san = string(rune(pieceType))
+		if others != 0 {
+			sq := bits.TrailingZeros64(from)
+			switch {
+			case others&fileMasks[squareFile(sq)] == 0:
+				san += uciSquare(from)[:1]
+			case others&(bottomEdge<<(8*uint(squareRank(sq)))) == 0:
+				san += uciSquare(from)[1:]
+			default:
+				san += uciSquare(from)
+			}
+		}
+		if m.isCapture() {
+			san += "x"
+		}
+		san += uciSquare(to)
+	}
+
+	undo := b.doMove(m, isWhite)
+	if b.isCheck(!isWhite) {
+		if b.hasLegalMove(!isWhite) {
+			san += "+"
+		} else {
+			san += "#"
+		}
+	}
+	b.undoMove(m, isWhite, undo)
+	return san
+}
+
+// pieceName returns the name of a piece type in lower case, e.g. knight.
+func pieceName(pieceType PieceType) string {
+	switch pieceType {
+	case Pawn:
+		return "pawn"
+	case Knight:
+		return "knight"
+	case Bishop:
+		return "bishop"
+	case Rook:
+		return "rook"
+	case Queen:
+		return "queen"
+	case King:
+		return "king"
+	}
+	return "piece"
+}
+
+// isSquare reports whether s names a square, e.g. e4.
+func isSquare(s string) bool {
+	return len(s) == 2 && s[0] >= 'a' && s[0] <= 'h' && s[1] >= '1' && s[1] <= '8'
+}
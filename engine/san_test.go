@@ -0,0 +1,106 @@
+package engine
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestFormatSAN(t *testing.T) {
+	tests := []struct {
+		position, uci, san string
+	}{
+		{"startpos", "e2e4", "e4"},
+		{"startpos", "g1f3", "Nf3"},
+		{"startpos moves e2e4 d7d5", "e4d5", "exd5"},
+		{"startpos moves e2e4 a7a6 e4e5 d7d5", "e5d6", "exd6"},
+		{"fen r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "e1g1", "O-O"},
+		{"fen r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1", "e8c8", "O-O-O"},
+		{"fen 4k3/8/8/8/8/8/8/R3K2R w K - 0 1", "a1a8", "Ra8+"},
+		{"fen 6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1", "a1a8", "Ra8#"},
+		{"fen 4k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "b7b8q", "b8=Q+"},
+		{"fen 4k3/8/8/8/8/8/8/R4R1K w - - 0 1", "a1d1", "Rad1"},
+		{"fen 4k3/8/8/R7/8/8/8/R3K3 w - - 0 1", "a1a3", "R1a3"},
+		{"fen 4k3/8/8/8/8/2N3N1/8/2N1K3 w - - 0 1", "c3e2", "Nc3e2"},
+	}
+	for _, tt := range tests {
+		g := newTestGame(t, tt.position)
+		m, err := g.parseUCIMove(tt.uci)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := g.formatSAN(m); got != tt.san {
+			t.Errorf("%s: %s written %s, want %s", tt.position, tt.uci, got, tt.san)
+		}
+	}
+}
+
+// TestSANRoundTrip writes every legal move along random games in SAN and reads it back.
+func TestSANRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	randomPlayouts(t, r, boardTestFens(), 60, func(context string, b *Board, whiteToMove bool) {
+		g := Game{board: *b, whiteToMove: whiteToMove}
+		var moves MoveList
+		g.board.generateMoves(whiteToMove, &moves)
+		for _, m := range moves.slice() {
+			san := g.formatSAN(m)
+			if got, err := g.parseSAN(san); err != nil || got != m {
+				t.Fatalf("%s: %v written %s reads back as %v, %v", context, m, san, got, err)
+			}
+		}
+	})
+}
+
+func TestParseSANErrors(t *testing.T) {
+	tests := []struct {
+		position, san, err string
+	}{
+		{"startpos", "Nd2", "your own pawn stands on d2"},
+		{"startpos", "Ke2", "your own pawn stands on e2"},
+		{"startpos", "Nxf3", "nothing to take on f3"},
+		{"startpos", "exd3", "nothing to take on d3"},
+		{"startpos", "Nc3x", "invalid move"},
+		{"startpos", "Nxxc3", "invalid move"},
+		{"startpos", "xNc3", "invalid move"},
+		{"startpos", "e5", "no pawn can go to e5"},
+		{"startpos", "Qh5", "no queen can go to h5"},
+		{"startpos moves e2e4 e7e5", "e5", "takes diagonally"},
+		{"startpos moves e2e4 e7e5", "Kf1", "your own bishop stands on f1"},
+		{"fen 4k3/8/8/8/8/8/4r3/4K3 w - - 0 1", "Kd2", "leaves the king in check"},
+		{"fen 4k3/4r3/8/8/8/8/4B3/4K3 w - - 0 1", "Bd3", "leaves the king in check"},
+		{"fen 4k3/8/8/8/8/8/8/R4R1K w - - 0 1", "Rd1", "could be Rfd1 or Rad1"},
+		{"fen 4k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "b8", "must promote"},
+		{"fen 4k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "b8=K", "promotes to N, B, R or Q"},
+		{"fen 4k3/8/8/8/8/8/1P6/4K3 w - - 0 1", "b4=Q", "only a pawn reaching the last rank promotes"},
+		{"fen 4k3/8/8/8/8/8/8/R3K2R w - - 0 1", "O-O", "castling is not possible"},
+	}
+	for _, tt := range tests {
+		g := newTestGame(t, tt.position)
+		_, err := g.parseSAN(tt.san)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: %s gave error %v, want %q", tt.position, tt.san, err, tt.err)
+		}
+	}
+}
+
+func TestParseSANAcceptsVariants(t *testing.T) {
+	tests := []struct {
+		position, san, uci string
+	}{
+		{"startpos moves e2e4 d7d5", "exd5", "e4d5"},
+		{"startpos moves e2e4 d7d5", "ed5", "e4d5"},
+		{"startpos moves e2e4 a7a6 e4e5 d7d5", "exd6", "e5d6"},
+		{"startpos", "Nf3+!?", "g1f3"},
+		{"fen 4k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "b8Q", "b7b8q"},
+		{"fen 3rk3/2P5/8/8/8/8/8/4K3 w - - 0 1", "cxd8=N", "c7d8n"},
+		{"fen r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "0-0-0", "e1c1"},
+		{"fen 4k3/8/8/8/8/2N3N1/8/2N1K3 w - - 0 1", "Nc3e2", "c3e2"},
+	}
+	for _, tt := range tests {
+		g := newTestGame(t, tt.position)
+		m, err := g.parseSAN(tt.san)
+		if err != nil || m.String() != tt.uci {
+			t.Errorf("%s: %s read as %v, %v; want %s", tt.position, tt.san, m, err, tt.uci)
+		}
+	}
+}
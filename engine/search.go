@@ -1,143 +1,217 @@
 package engine
 
-func (b *Board) alphaBetaMiniMax(isWhite bool, alpha, beta float64, depth int) (float64, [2]uint64) {
+import (
+	"fmt"
+	"sort"
+)
+
+// uciScore formats a score for a uci info line. UCI scores are from the point of view of
+// the side to move, and mates are given in moves rather than centipawns.
+func uciScore(score Score, whiteToMove bool) string {
+	if !whiteToMove {
+		score = -score
+	}
+	if score > scoreMateBound {
+		return fmt.Sprintf("mate %d", (scoreMate-score+1)/2)
+	}
+	if score < -scoreMateBound {
+		return fmt.Sprintf("mate %d", -(scoreMate+score)/2)
+	}
+	return fmt.Sprintf("cp %d", score)
+}
+
+// searchPath holds the position keys from the start of the game down to the node
+// currently being searched, so the search can recognise repetitions.
+var searchPath []uint64
+
+// searchRootLen is the length of searchPath at the root of the current search.
+var searchRootLen int
+
+// startSearch seeds the search path with the positions played so far in the game.
+func startSearch(history []uint64) {
+	searchPath = append(searchPath[:0], history...)
+	searchRootLen = len(searchPath)
+	tbHits = 0
+}
+
+// nextHalfmoveClock returns the fifty-move counter after a move: captures and pawn moves reset it.
+func nextHalfmoveClock(halfmoveClock int, pieceType PieceType, wasPieceCaptured bool) int {
+	if wasPieceCaptured || pieceType == Pawn {
+		return 0
+	}
+	return halfmoveClock + 1
+}
+
+// isSearchDraw reports whether the node at the end of the search path is drawn by the
+// fifty-move rule or by repetition. Inside the search a single repetition is enough,
+// as whatever was best the first time will be best again.
+func isSearchDraw(halfmoveClock int) bool {
+	if halfmoveClock >= 100 {
+		return true
+	}
+	last := len(searchPath) - 1
+	for i := last - 2; i >= 0 && i >= last-halfmoveClock; i -= 2 {
+		if searchPath[i] == searchPath[last] {
+			return true
+		}
+	}
+	return false
+}
+
+// pushSearchPosition adds the position reached after a move to the search path.
+func (b *Board) pushSearchPosition(whiteToMove bool) {
+	searchPath = append(searchPath, b.positionKey(whiteToMove))
+}
+
+// popSearchPosition removes the last position added to the search path.
+func popSearchPosition() {
+	searchPath = searchPath[:len(searchPath)-1]
+}
+
+func (b *Board) alphaBetaMiniMax(isWhite bool, alpha, beta Score, depth int, halfmoveClock int) (Score, Move) {
+	if len(searchPath) > searchRootLen && isSearchDraw(halfmoveClock) {
+		return scoreDraw, noMove
+	}
+	// a capture or pawn move into a tablebase position settles the result
+	if len(searchPath) > searchRootLen && halfmoveClock == 0 {
+		if score, ok := b.probeTablebaseScore(isWhite, len(searchPath)-searchRootLen); ok {
+			return score, noMove
+		}
+	}
 	if depth == 0 {
-		a, c := b.eval()
-		return a + c, [2]uint64{0, 0}
+		return b.quiescence(isWhite, alpha, beta), noMove
 	}
-	if isWhite {
-		lastMove := [2]uint64{0, 0}
-		bestMove := [2]uint64{0, 0}
-		for _, move := range b.getAllLegalMoves(isWhite) {
-			var to_break bool = false
-			for i := 0; i < 64; i++ {
-				var cur_pos uint64 = 1 << uint64(i)
-				if move[1]&cur_pos != 0 {
-					lastMove = [2]uint64{move[0], cur_pos}
-
-					//
-					var wasPieceCaptured bool
-					var capturedPieceType PieceType
-					var kingmove [2]uint64 = [2]uint64{0, 0}
-					isCastleMove, valueReceived := checkMoveIsCastle(move)
-
-					if isCastleMove {
-						b.makeMove(b.notationToPos(castleMoveInfo[valueReceived].KingMove[1:3]), cur_pos, isWhite, King)
-						b.makeMove(b.notationToPos(castleMoveInfo[valueReceived].RookMove[3:5]), cur_pos, isWhite, Rook)
-						castleMoveInfo[valueReceived].CanCastle = false
-					} else {
-						wasPieceCaptured, capturedPieceType = b.makeMove(move[0], cur_pos, isWhite, b.getPieceType(move[0]))
-						if (kingmove != [2]uint64{0, 0}) && (b.getPieceType(move[0]) == King) {
-							kingmove = move
-							castleMoveInfo["O-O"].CanCastle = false
-							castleMoveInfo["O-O-O"].CanCastle = false
-						}
-					}
-
-					moveAlpha, _ := b.alphaBetaMiniMax(!isWhite, alpha, beta, depth-1)
-					if isCastleMove {
-						b.unmakeMove(b.notationToPos(castleMoveInfo[valueReceived].KingMove[1:3]), cur_pos, isWhite, false, NoPiece)
-						b.unmakeMove(b.notationToPos(castleMoveInfo[valueReceived].RookMove[3:5]), cur_pos, isWhite, false, NoPiece)
-						castleMoveInfo[valueReceived].CanCastle = true
-					} else {
-						b.unmakeMove(move[0], cur_pos, isWhite, wasPieceCaptured, capturedPieceType)
-						if kingmove == move {
-							kingmove = [2]uint64{0, 0}
-							castleMoveInfo["O-O"].CanCastle = true
-							castleMoveInfo["O-O-O"].CanCastle = true
-						}
-					}
-					//
-
-					if moveAlpha > alpha {
-						alpha = moveAlpha
-						bestMove = [2]uint64{move[0], cur_pos}
-					}
-					if beta <= alpha {
-						to_break = true
-						break
-					}
-				}
-			}
-			if to_break {
-				break
-			}
+	var legalMoves MoveList
+	b.generateMoves(isWhite, &legalMoves)
+	if legalMoves.len() == 0 {
+		if !b.isCheck(isWhite) {
+			return scoreDraw, noMove // stalemate
 		}
-		if bestMove == [2]uint64{0, 0} {
-			bestMove = lastMove
+		// mated: the fewer plies from the root, the better the mate for the winner
+		ply := Score(len(searchPath) - searchRootLen)
+		if isWhite {
+			return -(scoreMate - ply), noMove
 		}
+		return scoreMate - ply, noMove
+	}
 
+	bestMove := noMove
+	ordered := b.orderMoves(legalMoves.slice())
+	for _, move := range ordered {
+		clock := nextHalfmoveClock(halfmoveClock, b.getPieceType(move.from()), move.isCapture())
+		undo := b.doMove(move, isWhite)
+		b.pushSearchPosition(!isWhite)
+		score, _ := b.alphaBetaMiniMax(!isWhite, alpha, beta, depth-1, clock)
+		popSearchPosition()
+		b.undoMove(move, isWhite, undo)
+
+		// white raises alpha and black lowers beta
+		if isWhite && score > alpha {
+			alpha, bestMove = score, move
+		} else if !isWhite && score < beta {
+			beta, bestMove = score, move
+		}
+		if beta <= alpha {
+			break
+		}
+	}
+	if bestMove == noMove {
+		// nothing beat the bounds, but the caller still needs a move to play
+		bestMove = ordered[0]
+	}
+	if isWhite {
 		return alpha, bestMove
-	} else {
-		lastMove := [2]uint64{0, 0}
-		bestMove := [2]uint64{0, 0}
-		for _, move := range b.getAllLegalMoves(isWhite) {
-			var to_break bool = false
-			for i := 0; i < 64; i++ {
-				var cur_pos uint64 = 1 << uint64(i)
-				if move[1]&cur_pos != 0 {
-					lastMove = [2]uint64{move[0], cur_pos}
-
-					//
-					var wasPieceCaptured bool
-					var capturedPieceType PieceType
-					var kingmove [2]uint64 = [2]uint64{0, 0}
-					isCastleMove, valueReceived := checkMoveIsCastle(move)
-					if isCastleMove {
-						b.makeMove(b.notationToPos(castleMoveInfo[valueReceived].KingMove[1:3]), cur_pos, isWhite, King)
-						b.makeMove(b.notationToPos(castleMoveInfo[valueReceived].RookMove[3:5]), cur_pos, isWhite, Rook)
-						castleMoveInfo[valueReceived].CanCastle = false
-					} else {
-						wasPieceCaptured, capturedPieceType = b.makeMove(move[0], cur_pos, isWhite, b.getPieceType(move[0]))
-						if (kingmove != [2]uint64{0, 0}) && (b.getPieceType(move[0]) == King) {
-							kingmove = move
-							castleMoveInfo["o-o"].CanCastle = false
-							castleMoveInfo["o-o-o"].CanCastle = false
-						}
-					}
-					moveBeta, _ := b.alphaBetaMiniMax(!isWhite, alpha, beta, depth-1)
-					if isCastleMove {
-						b.unmakeMove(b.notationToPos(castleMoveInfo[valueReceived].KingMove[1:3]), cur_pos, isWhite, false, NoPiece)
-						b.unmakeMove(b.notationToPos(castleMoveInfo[valueReceived].RookMove[3:5]), cur_pos, isWhite, false, NoPiece)
-						castleMoveInfo[valueReceived].CanCastle = true
-					} else {
-						b.unmakeMove(move[0], cur_pos, isWhite, wasPieceCaptured, capturedPieceType)
-						if kingmove == move {
-							kingmove = [2]uint64{0, 0}
-							castleMoveInfo["o-o"].CanCastle = true
-							castleMoveInfo["o-o-o"].CanCastle = true
-						}
-					}
-
-					if moveBeta < beta {
-						beta = moveBeta
-						bestMove = [2]uint64{move[0], cur_pos}
-					}
-					if beta <= alpha {
-						to_break = true
-						break
-					}
-				}
-				if to_break {
-					break
-				}
+	}
+	return beta, bestMove
+}
 
+// orderMoves sorts the legal moves in place so the most promising are searched first,
+// which makes alpha-beta cut off sooner: captures that win material by see first, then
+// quiet moves, then losing captures.
+func (b *Board) orderMoves(moves []Move) []Move {
+	scores := make([]int, len(moves))
+	for i, move := range moves {
+		if move.isCapture() {
+			scores[i] = b.see(move)
+			if scores[i] >= 0 {
+				scores[i] += evalParams.PieceValues.King // keep even trades ahead of quiet moves
 			}
-
 		}
-		if bestMove == [2]uint64{0, 0} {
-			bestMove = lastMove
+		if move.promotion() == Queen {
+			scores[i] += evalParams.PieceValues.Queen
 		}
-		return beta, bestMove
 	}
+	sort.Stable(scoredMoves{moves, scores})
+	return moves
+}
+
+// scoredMoves sorts moves by score, highest first.
+type scoredMoves struct {
+	moves  []Move
+	scores []int
+}
+
+func (s scoredMoves) Len() int           { return len(s.moves) }
+func (s scoredMoves) Less(i, j int) bool { return s.scores[i] > s.scores[j] }
+func (s scoredMoves) Swap(i, j int) {
+	s.moves[i], s.moves[j] = s.moves[j], s.moves[i]
+	s.scores[i], s.scores[j] = s.scores[j], s.scores[i]
+}
 
+// captureMoves adds the legal captures of a side that do not lose material according
+// to see to the list, best first.
+func (b *Board) captureMoves(isWhite bool, captures *MoveList) {
+	var moves MoveList
+	b.generateMoves(isWhite, &moves)
+	var scores []int
+	for _, move := range moves.slice() {
+		if !move.isCapture() {
+			continue
+		}
+		if score := b.see(move); score >= 0 {
+			captures.add(move)
+			scores = append(scores, score)
+		}
+	}
+	sort.Stable(scoredMoves{captures.slice(), scores})
 }
 
-func checkMoveIsCastle(move [2]uint64) (bool, string) {
-	for key, value := range castleMoveInfo {
-		if value.RandomMove == move {
-			return true, key
+// quiescence carries the search on past the depth limit with captures only, so the
+// static eval is never taken in the middle of an exchange. The side to move may always
+// stand pat on the static eval instead of capturing, and captures that lose material
+// by see are not tried at all.
+func (b *Board) quiescence(isWhite bool, alpha, beta Score) Score {
+	standPat := evaluator.Evaluate(b, isWhite)
+	if isWhite {
+		if standPat >= beta {
+			return standPat
+		}
+		alpha = max(alpha, standPat)
+	} else {
+		if standPat <= alpha {
+			return standPat
+		}
+		beta = min(beta, standPat)
+	}
+
+	var captures MoveList
+	b.captureMoves(isWhite, &captures)
+	for _, move := range captures.slice() {
+		undo := b.doMove(move, isWhite)
+		score := b.quiescence(!isWhite, alpha, beta)
+		b.undoMove(move, isWhite, undo)
+
+		if isWhite {
+			alpha = max(alpha, score)
+		} else {
+			beta = min(beta, score)
 		}
+		if beta <= alpha {
+			break
+		}
+	}
+	if isWhite {
+		return alpha
 	}
-	return false, ""
+	return beta
 }
@@ -0,0 +1,63 @@
+package engine
+
+import "testing"
+
+// searchTestGame searches a position to the given depth and returns the score from
+// white's point of view and the move found.
+func searchTestGame(t *testing.T, fen string, depth int) (Score, Move) {
+	t.Helper()
+	g := parseGame(fen)
+	startSearch(g.history)
+	return g.board.alphaBetaMiniMax(g.whiteToMove, -scoreInfinite, scoreInfinite, depth, g.halfmoveClock)
+}
+
+func TestUCIScore(t *testing.T) {
+	tests := []struct {
+		score       Score
+		whiteToMove bool
+		want        string
+	}{
+		{35, true, "cp 35"},
+		{35, false, "cp -35"},
+		{scoreMate - 1, true, "mate 1"},
+		{scoreMate - 3, true, "mate 2"},
+		{scoreMate - 3, false, "mate -1"},
+		{-(scoreMate - 2), true, "mate -1"},
+		{-(scoreMate - 1), false, "mate 1"},
+	}
+	for _, tt := range tests {
+		if got := uciScore(tt.score, tt.whiteToMove); got != tt.want {
+			t.Errorf("uciScore(%d, %v) = %q, want %q", tt.score, tt.whiteToMove, got, tt.want)
+		}
+	}
+}
+
+func TestSearchFindsMate(t *testing.T) {
+	tests := []struct {
+		fen   string
+		depth int
+		move  string
+		score Score
+	}{
+		{"6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1", 2, "a1a8", scoreMate - 1},
+		{"r5k1/8/8/8/8/8/5PPP/6K1 b - - 0 1", 2, "a8a1", -(scoreMate - 1)},
+		// mate in two: the rook cuts the king off, then the other rook mates
+		{"7k/8/8/8/8/8/8/RR4K1 w - - 0 1", 4, "", scoreMate - 3},
+	}
+	for _, tt := range tests {
+		score, move := searchTestGame(t, tt.fen, tt.depth)
+		if score != tt.score {
+			t.Errorf("%s: score %d, want %d", tt.fen, score, tt.score)
+		}
+		if tt.move != "" && move.String() != tt.move {
+			t.Errorf("%s: move %s, want %s", tt.fen, move, tt.move)
+		}
+	}
+}
+
+func TestSearchScoresStalemateAsDraw(t *testing.T) {
+	// black to move has no legal move and is not in check
+	if score, _ := searchTestGame(t, "k7/2Q5/1K6/8/8/8/8/8 b - - 0 1", 1); score != scoreDraw {
+		t.Errorf("stalemated side scores %d, want a draw", score)
+	}
+}
@@ -0,0 +1,119 @@
+package engine
+
+import "math/bits"
+
+// seeValue is the value of each piece type when trading material in an exchange.
+func seeValue(pieceType PieceType) int {
+	return evalParams.PieceValues.of(pieceType)
+}
+
+// slidingAttacks returns the squares attacked from pos along the diagonals, the
+// straight lines or both, stopping at the first occupied square of each ray. Unlike
+// the move generators, blockers of either colour are included, and occupancy is
+// passed in so that pieces which already took part in an exchange can be treated as gone.
+func slidingAttacks(pos, occupied uint64, dirs uint8) uint64 {
+	var attacks uint64
+	if dirs&diagDirs != 0 {
+		attacks |= bishopAttacks(pos, occupied)
+	}
+	if dirs&straightDirs != 0 {
+		attacks |= rookAttacks(pos, occupied)
+	}
+	return attacks
+}
+
+// pawnAttacks returns the squares a pawn on pos attacks.
+func pawnAttacks(pos uint64, isWhite bool) uint64 {
+	if isWhite {
+		return (pos&^leftEdge)<<9 | (pos&^rightEdge)<<7
+	}
+	return (pos&^rightEdge)>>9 | (pos&^leftEdge)>>7
+}
+
+// knightAttacks returns the squares a knight on pos attacks.
+func knightAttacks(pos uint64) uint64 {
+	return knightAttackTable[bits.TrailingZeros64(pos)]
+}
+
+// kingAttacks returns the squares a king on pos attacks.
+func kingAttacks(pos uint64) uint64 {
+	return kingAttackTable[bits.TrailingZeros64(pos)]
+}
+
+// attackersTo returns the pieces of both colours that attack the square pos,
+// given the occupancy of the board.
+func (b *Board) attackersTo(pos, occupied uint64) uint64 {
+	diagSliders := b.kindBitboard(bishopIndex) | b.kindBitboard(queenIndex)
+	straightSliders := b.kindBitboard(rookIndex) | b.kindBitboard(queenIndex)
+
+	sq := bits.TrailingZeros64(pos)
+	attackers := pawnAttackTable[1][sq] & b.pieces[whiteIndex][pawnIndex]
+	attackers |= pawnAttackTable[0][sq] & b.pieces[blackIndex][pawnIndex]
+	attackers |= knightAttacks(pos) & b.kindBitboard(knightIndex)
+	attackers |= kingAttacks(pos) & b.kindBitboard(kingIndex)
+	attackers |= slidingAttacks(pos, occupied, diagDirs) & diagSliders
+	attackers |= slidingAttacks(pos, occupied, straightDirs) & straightSliders
+	return attackers & occupied
+}
+
+// leastValuableAttacker picks the cheapest piece among attackers.
+func (b *Board) leastValuableAttacker(attackers uint64) (uint64, PieceType) {
+	var best uint64
+	var bestType PieceType = NoPiece
+	for attackers != 0 {
+		pos := attackers & -attackers
+		attackers &= attackers - 1
+		pieceType := b.getPieceType(pos)
+		if best == 0 || seeValue(pieceType) < seeValue(bestType) {
+			best, bestType = pos, pieceType
+		}
+	}
+	return best, bestType
+}
+
+// see runs the static exchange evaluation of a move: it plays out the whole sequence
+// of captures on the destination square, each side always recapturing with its least
+// valuable piece and free to stop when continuing would lose material, and returns
+// the material the moving side gains. Sliders lined up behind one another (x-rays)
+// join in as the pieces in front of them are traded off.
+func (b *Board) see(move Move) int {
+	from, to := move.from(), move.to()
+	var gain [32]int
+	d := 0
+
+	gain[0] = seeValue(b.getPieceType(to))
+	attackerType := b.getPieceType(from)
+	isWhite := !b.getColour(from)
+	occupied := b.allPieces &^ from
+	if move.isEnPassant() {
+		gain[0] = seeValue(Pawn)
+		occupied &^= enPassantVictim(to, !isWhite)
+	}
+
+	for {
+		d++
+		// score if the piece just moved to the square is captured in turn
+		gain[d] = seeValue(attackerType) - gain[d-1]
+		if max(-gain[d-1], gain[d]) < 0 {
+			// neither side wants to continue the exchange
+			break
+		}
+
+		attackers := b.attackersTo(to, occupied) & b.colours[colourIndex(isWhite)]
+		if attackers == 0 {
+			break
+		}
+		var pos uint64
+		pos, attackerType = b.leastValuableAttacker(attackers)
+		occupied &^= pos
+		isWhite = !isWhite
+		if d == len(gain)-1 {
+			break
+		}
+	}
+
+	for d--; d > 0; d-- {
+		gain[d-1] = -max(-gain[d-1], gain[d])
+	}
+	return gain[0]
+}
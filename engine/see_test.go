@@ -0,0 +1,33 @@
+package engine
+
+import "testing"
+
+func TestSEE(t *testing.T) {
+	p, n, r := seeValue(Pawn), seeValue(Knight), seeValue(Rook)
+	tests := []struct {
+		fen  string
+		move string
+		want int
+	}{
+		// an undefended knight
+		{"4k3/8/8/3n4/4P3/8/8/4K3 w - - 0 1", "e4d5", n},
+		// a pawn defended by a pawn, taken by a rook
+		{"4k3/8/3p4/4p3/8/8/8/K3R3 w - - 0 1", "e1e5", p - r},
+		// a rook behind the rook that takes joins in through the x-ray
+		{"1k1r4/1pp4p/p7/4p3/8/P5P1/1PP4P/2K1R3 w - - 0 1", "e1e5", p},
+		{"1k1r3q/1ppn3p/p4b2/4p3/8/P2N2P1/1PP1R1BP/2K1Q3 w - - 0 1", "d3e5", p - n},
+		// the queen would be lost to the rook behind, so it does not take back
+		{"3qk3/8/8/3r4/8/8/3R4/3RK3 w - - 0 1", "d2d5", r},
+		{"4k3/8/8/8/3pP3/8/8/4K3 b - e3 0 1", "d4e3", p},
+	}
+	for _, tt := range tests {
+		g := newTestGame(t, "fen "+tt.fen)
+		m, err := g.parseUCIMove(tt.move)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.fen, err)
+		}
+		if got := g.board.see(m); got != tt.want {
+			t.Errorf("see(%s) in %s = %d, want %d", tt.move, tt.fen, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package engine
+
+import "math/bits"
+
+// WDL is a tablebase result for the side to move. Cursed wins and blessed losses are
+// wins and losses that the fifty-move rule turns into draws.
+type WDL int
+
+const (
+	WDLLoss        WDL = -2
+	WDLBlessedLoss WDL = -1
+	WDLDraw        WDL = 0
+	WDLCursedWin   WDL = 1
+	WDLWin         WDL = 2
+)
+
+// scoreTBWin is the score of a tablebase win found ply plies from the root, less ply.
+// It sits below the mate scores so that a real mate is still preferred.
+const scoreTBWin Score = scoreMateBound - maxPly
+
+// Tablebase answers questions about positions with few pieces left.
+type Tablebase interface {
+	// MaxPieces is the most pieces, kings included, a position may have to be probed.
+	MaxPieces() int
+	// ProbeWDL returns the result of the position with perfect play.
+	ProbeWDL(b *Board, whiteToMove bool) (WDL, bool)
+	// ProbeDTZ returns the distance in plies to the next capture or pawn move that
+	// keeps the result, positive when the side to move wins and negative when it loses.
+	ProbeDTZ(b *Board, whiteToMove bool) (int, bool)
+}
+
+// tablebase is the tablebase used by the search, nil when there is none.
+var tablebase Tablebase
+
+// tbHits counts successful tablebase probes during the current search.
+var tbHits uint64
+
+// SetTablebase makes the search use the given tablebase, or none if it is nil.
+func SetTablebase(tb Tablebase) {
+	tablebase = tb
+}
+
+// inTablebase reports whether the board has few enough pieces to be probed.
+func (b *Board) inTablebase() bool {
+	return tablebase != nil && bits.OnesCount64(b.allPieces) <= tablebase.MaxPieces()
+}
+
+// probeTablebaseScore looks the board up in the tablebase and turns the result into a
+// search score from white's point of view, ply plies from the root.
+func (b *Board) probeTablebaseScore(whiteToMove bool, ply int) (Score, bool) {
+	if !b.inTablebase() {
+		return 0, false
+	}
+	wdl, ok := tablebase.ProbeWDL(b, whiteToMove)
+	if !ok {
+		return 0, false
+	}
+	tbHits++
+	score := scoreDraw
+	switch wdl {
+	case WDLWin:
+		score = scoreTBWin - Score(ply)
+	case WDLLoss:
+		score = -scoreTBWin + Score(ply)
+	}
+	if !whiteToMove {
+		score = -score
+	}
+	return score, true
+}
+
+// tablebaseMove picks the move at the root from the tablebase: the quickest win, the
+// slowest loss or a move that holds the draw. ok is false when a move cannot be probed.
+func (g *Game) tablebaseMove() (Move, bool) {
+	b := &g.board
+	if !b.inTablebase() {
+		return noMove, false
+	}
+	isWhite := g.whiteToMove
+	var moves MoveList
+	b.generateMoves(isWhite, &moves)
+	best, bestRank := noMove, 0
+	for _, move := range moves.slice() {
+		zeroing := move.isCapture() || b.getPieceType(move.from()) == Pawn
+		undo := b.doMove(move, isWhite)
+		rank, probed := rankTablebaseMove(b, !isWhite, zeroing)
+		b.undoMove(move, isWhite, undo)
+		if !probed {
+			return noMove, false
+		}
+		tbHits++
+		if best == noMove || rank > bestRank {
+			best, bestRank = move, rank
+		}
+	}
+	return best, best != noMove
+}
+
+// rankTablebaseMove scores the position after a root move for the side that made it:
+// wins rank above draws above losses, quicker wins and slower losses rank higher, and
+// a capture or pawn move that wins ranks above any other win as it resets the count.
+func rankTablebaseMove(b *Board, opponentToMove bool, zeroing bool) (int, bool) {
+	wdl, ok := tablebase.ProbeWDL(b, opponentToMove)
+	if !ok {
+		return 0, false
+	}
+	dtz, ok := tablebase.ProbeDTZ(b, opponentToMove)
+	if !ok {
+		dtz = 0
+	}
+	if dtz < 0 {
+		dtz = -dtz
+	}
+	switch {
+	case wdl == WDLLoss && zeroing:
+		return 3000, true
+	case wdl == WDLLoss:
+		return 2000 - dtz, true
+	case wdl == WDLBlessedLoss:
+		return 1000 - dtz, true
+	case wdl == WDLWin:
+		return -2000 + dtz, true
+	case wdl == WDLCursedWin:
+		return -1000 + dtz, true
+	}
+	return 0, true
+}
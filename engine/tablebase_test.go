@@ -0,0 +1,113 @@
+package engine
+
+import (
+	"math/bits"
+	"testing"
+)
+
+// materialTablebase is a made-up tablebase for three and four pieces: the side with
+// more material wins, in as many plies as the kings are apart.
+type materialTablebase struct{}
+
+func (materialTablebase) MaxPieces() int {
+	return 4
+}
+
+func (materialTablebase) ProbeWDL(b *Board, whiteToMove bool) (WDL, bool) {
+	white, black := b.evalMaterialValues()
+	diff := white.mg - black.mg
+	if !whiteToMove {
+		diff = -diff
+	}
+	switch {
+	case diff > 0:
+		return WDLWin, true
+	case diff < 0:
+		return WDLLoss, true
+	}
+	return WDLDraw, true
+}
+
+func (tb materialTablebase) ProbeDTZ(b *Board, whiteToMove bool) (int, bool) {
+	wdl, _ := tb.ProbeWDL(b, whiteToMove)
+	white, black := bits.TrailingZeros64(b.pieces[whiteIndex][kingIndex]), bits.TrailingZeros64(b.pieces[blackIndex][kingIndex])
+	distance := max(abs(squareFile(white)-squareFile(black)), abs(squareRank(white)-squareRank(black)))
+	switch wdl {
+	case WDLWin:
+		return distance, true
+	case WDLLoss:
+		return -distance, true
+	}
+	return 0, true
+}
+
+func withTablebase(t *testing.T, tb Tablebase) {
+	t.Helper()
+	SetTablebase(tb)
+	t.Cleanup(func() { SetTablebase(nil) })
+}
+
+func TestProbeTablebaseScore(t *testing.T) {
+	withTablebase(t, materialTablebase{})
+	tests := []struct {
+		fen  string
+		ply  int
+		want Score
+		ok   bool
+	}{
+		{"8/8/8/4k3/8/8/8/R3K3 w - - 0 1", 3, scoreTBWin - 3, true},
+		{"8/8/8/4k3/8/8/8/R3K3 b - - 0 1", 3, scoreTBWin - 3, true},
+		{"8/8/8/4k3/8/8/8/r3K3 w - - 0 1", 2, -scoreTBWin + 2, true},
+		{"8/8/8/4k3/8/8/8/r3KR2 w - - 0 1", 2, scoreDraw, true},
+		{"8/8/8/4k3/8/8/8/rR2KR2 w - - 0 1", 2, 0, false},
+	}
+	for _, tt := range tests {
+		tbHits = 0
+		g := parseGame(tt.fen)
+		score, ok := g.board.probeTablebaseScore(g.whiteToMove, tt.ply)
+		if score != tt.want || ok != tt.ok {
+			t.Errorf("%s: probe gave %d %v, want %d %v", tt.fen, score, ok, tt.want, tt.ok)
+		}
+		if want := map[bool]uint64{true: 1}[tt.ok]; tbHits != want {
+			t.Errorf("%s: %d tablebase hits, want %d", tt.fen, tbHits, want)
+		}
+	}
+}
+
+func TestTablebaseMove(t *testing.T) {
+	withTablebase(t, materialTablebase{})
+	tests := []struct {
+		fen, want string
+	}{
+		// taking the knight wins and resets the count, which beats any other win
+		{"k7/8/8/8/8/8/8/3nK2R w - - 0 1", "e1d1"},
+		// otherwise the quickest win: the move that brings the kings closest
+		{"k7/8/8/8/8/8/8/1R5K w - - 0 1", "h1g2"},
+		// the losing side picks the slowest loss
+		{"k7/8/8/8/8/8/8/4K2R b - - 0 1", "a8b8"},
+	}
+	for _, tt := range tests {
+		g := parseGame(tt.fen)
+		move, ok := g.tablebaseMove()
+		if !ok || move.String() != tt.want {
+			t.Errorf("%s: tablebase move %v %v, want %s", tt.fen, move, ok, tt.want)
+		}
+	}
+
+	g := parseGame(startPosition)
+	if _, ok := g.tablebaseMove(); ok {
+		t.Error("tablebase move found in the start position")
+	}
+}
+
+func TestSearchStopsAtTablebase(t *testing.T) {
+	withTablebase(t, materialTablebase{})
+	// five pieces, so only the capture reaches the tablebase
+	score, move := searchTestGame(t, "k7/p7/8/8/8/8/8/3nK2R w - - 0 1", 2)
+	if move.String() != "e1d1" || score != scoreTBWin-1 {
+		t.Errorf("search gave %v with %d, want e1d1 with %d", move, score, scoreTBWin-1)
+	}
+	if tbHits == 0 {
+		t.Error("search counted no tablebase hits")
+	}
+}
@@ -0,0 +1,218 @@
+package engine
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// tuningPosition is a quiet position labelled with the result of the game it comes
+// from: 1 if white won, 0.5 for a draw and 0 if black won.
+type tuningPosition struct {
+	board       Board
+	whiteToMove bool
+	result      float64
+}
+
+// Tune fits the evaluation parameters to the positions in an EPD file by Texel's
+// method: it looks for the parameters whose static eval, mapped to an expected score
+// by a logistic curve, best predicts the game results, and writes them to outPath.
+// Each pass tries moving every parameter one centipawn up and down and keeps the
+// change if it lowers the error; tuning stops when a pass changes nothing or after
+// the given number of passes.
+func Tune(dataPath, outPath string, passes int) error {
+	positions, err := readTuningPositions(dataPath)
+	if err != nil {
+		return err
+	}
+	if len(positions) == 0 {
+		return fmt.Errorf("%s: no labelled positions found", dataPath)
+	}
+	fmt.Printf("Loaded %d positions\n", len(positions))
+
+	params := evalParams
+	defer SetEvalParams(params)
+
+	tuned := params
+	k := fitScalingConstant(positions, &tuned)
+	fmt.Printf("Scaling constant K = %.3f\n", k)
+
+	values := tuned.tunableValues()
+	bestError := tuningError(positions, &tuned, k)
+	fmt.Printf("Initial error: %.6f\n", bestError)
+
+	for pass := 1; pass <= passes; pass++ {
+		improved := 0
+		for _, value := range values {
+			for _, step := range []int{1, -1} {
+				*value += step
+				if e := tuningError(positions, &tuned, k); e < bestError {
+					bestError = e
+					improved++
+					break
+				}
+				*value -= step
+			}
+		}
+		fmt.Printf("Pass %d: error %.6f, %d parameters changed\n", pass, bestError, improved)
+		if err := tuned.Save(outPath); err != nil {
+			return err
+		}
+		if improved == 0 {
+			break
+		}
+	}
+	fmt.Printf("Parameters written to %s\n", outPath)
+	return nil
+}
+
+// readTuningPositions reads an EPD file with one position per line, followed by the
+// game result either as an opcode (c9 "1-0";) or as a bare result (1-0, 0-1, 1/2-1/2,
+// or [1.0], [0.5], [0.0]). Positions with the side to move in check are not quiet and
+// are skipped.
+func readTuningPositions(path string) ([]tuningPosition, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var positions []tuningPosition
+	scanner := bufio.NewScanner(file)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		result, ok := parseTuningResult(line)
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: no game result found", path, lineNo)
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("%s:%d: invalid position", path, lineNo)
+		}
+		g := parseGame(strings.Join(fields[:2], " "))
+		if g.board.pieces[whiteIndex][kingIndex] == 0 || g.board.pieces[blackIndex][kingIndex] == 0 {
+			return nil, fmt.Errorf("%s:%d: invalid position", path, lineNo)
+		}
+		if g.board.isCheck(g.whiteToMove) {
+			continue
+		}
+		positions = append(positions, tuningPosition{g.board, g.whiteToMove, result})
+	}
+	return positions, scanner.Err()
+}
+
+// parseTuningResult finds the game result on an EPD line.
+func parseTuningResult(line string) (float64, bool) {
+	labels := []struct {
+		label  string
+		result float64
+	}{
+		{"1/2-1/2", 0.5}, {"1-0", 1}, {"0-1", 0},
+		{"[0.5]", 0.5}, {"[1.0]", 1}, {"[0.0]", 0}, {"[1]", 1}, {"[0]", 0},
+	}
+	for _, l := range labels {
+		if strings.Contains(line, l.label) {
+			return l.result, true
+		}
+	}
+	return 0, false
+}
+
+// expectedScore maps an eval to the expected game result for white.
+func expectedScore(eval Score, k float64) float64 {
+	return 1 / (1 + math.Pow(10, -k*float64(eval)/400))
+}
+
+// tuningError is the mean squared difference between the game results and the
+// results the static eval with the given parameters predicts.
+func tuningError(positions []tuningPosition, params *EvalParams, k float64) float64 {
+	SetEvalParams(*params)
+	var sum float64
+	for i := range positions {
+		diff := positions[i].result - expectedScore(positions[i].board.eval(positions[i].whiteToMove), k)
+		sum += diff * diff
+	}
+	return sum / float64(len(positions))
+}
+
+// fitScalingConstant picks the K that makes the untuned eval predict the results
+// best, so tuning improves the parameters rather than just rescaling them.
+func fitScalingConstant(positions []tuningPosition, params *EvalParams) float64 {
+	best, bestError := 1.0, math.Inf(1)
+	for step := 1.0; step >= 0.001; step /= 10 {
+		start := math.Max(best-10*step, step)
+		for k := start; k <= best+10*step; k += step {
+			if e := tuningError(positions, params, k); e < bestError {
+				best, bestError = k, e
+			}
+		}
+	}
+	return best
+}
+
+// tunableValues returns pointers to every parameter the tuner may change. Values the
+// static eval does not depend on are left out: the king's value, which only matters to
+// see, the mobility averages, which only shift scores the tables already cover, the
+// pawn table rows and passed pawn bonuses for ranks pawns never stand on, the king
+// attack weights of pawns and kings, which are not counted as attackers, and the pawn
+// storm entry for a pawn on the king's own rank.
+func (p *EvalParams) tunableValues() []*int {
+	fixed := map[*int]bool{
+		&p.PieceValues.King:      true,
+		&p.KingAttackWeight.Pawn: true,
+		&p.KingAttackWeight.King: true,
+		&p.PawnStorm[0]:          true,
+	}
+	for _, v := range p.MobilityAverage.values() {
+		fixed[v] = true
+	}
+	for i := 0; i < 8; i++ {
+		fixed[&p.PieceSquareTables.Pawn.Mg[i]] = true
+		fixed[&p.PieceSquareTables.Pawn.Eg[i]] = true
+		fixed[&p.PieceSquareTables.Pawn.Mg[56+i]] = true
+		fixed[&p.PieceSquareTables.Pawn.Eg[56+i]] = true
+	}
+	for _, rank := range []int{0, 7} {
+		fixed[&p.PassedPawn[rank].mg] = true
+		fixed[&p.PassedPawn[rank].eg] = true
+	}
+
+	var values []*int
+	add := func(ptr *int) {
+		if !fixed[ptr] {
+			values = append(values, ptr)
+		}
+	}
+	var walk func(v reflect.Value)
+	walk = func(v reflect.Value) {
+		switch v.Kind() {
+		case reflect.Int:
+			add(v.Addr().Interface().(*int))
+		case reflect.Array:
+			for i := 0; i < v.Len(); i++ {
+				walk(v.Index(i))
+			}
+		case reflect.Struct:
+			if pair, ok := v.Addr().Interface().(*scorePair); ok {
+				add(&pair.mg)
+				add(&pair.eg)
+				return
+			}
+			for i := 0; i < v.NumField(); i++ {
+				walk(v.Field(i))
+			}
+		}
+	}
+	walk(reflect.ValueOf(p).Elem())
+	return values
+}
+
+func (w *PieceWeights) values() []*int {
+	return []*int{&w.Pawn, &w.Knight, &w.Bishop, &w.Rook, &w.Queen, &w.King}
+}
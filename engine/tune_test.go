@@ -0,0 +1,94 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTunableValuesLeaveOutUnreadWeights(t *testing.T) {
+	defer SetEvalParams(DefaultEvalParams())
+	params := DefaultEvalParams()
+	unread := []*int{
+		&params.PieceValues.King,
+		&params.KingAttackWeight.Pawn, &params.KingAttackWeight.King,
+		&params.PassedPawn[0].mg, &params.PassedPawn[0].eg,
+		&params.PassedPawn[7].mg, &params.PassedPawn[7].eg,
+		&params.PawnStorm[0],
+	}
+	tunable := map[*int]bool{}
+	for _, v := range params.tunableValues() {
+		tunable[v] = true
+	}
+	for i, v := range unread {
+		if tunable[v] {
+			t.Errorf("unread weight %d is tuned", i)
+		}
+	}
+	if !tunable[&params.PassedPawn[6].eg] || !tunable[&params.PawnStorm[1]] || !tunable[&params.KingAttackWeight.Queen] {
+		t.Error("weights the eval reads are left out of tuning")
+	}
+
+	// the weights left out really are unread: changing them changes no eval
+	fens := append([]string{
+		"6k1/1P3pp1/8/8/8/8/5PPP/6K1 w - - 0 1",
+		"r1bq1rk1/pppp1p2/2n2n2/4p3/4P1pp/2N2N2/PPPP1PPP/R1BQ1RK1 w - - 0 1",
+		"r1b2rk1/pppp1ppp/2n5/4p3/4P1nq/2N2N2/PPPP1PPP/R1BQ1RK1 w - - 0 1",
+	}, evalTestFens...)
+	var before []Score
+	for _, fen := range fens {
+		before = append(before, testBoard(fen).eval(true))
+	}
+	for _, v := range unread {
+		*v += 1000
+	}
+	SetEvalParams(params)
+	for i, fen := range fens {
+		if got := testBoard(fen).eval(true); got != before[i] {
+			t.Errorf("%s: eval %d after changing unread weights, was %d", fen, got, before[i])
+		}
+	}
+}
+
+func TestReadTuningPositions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "positions.epd")
+	data := `# comment
+rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - c9 "1/2-1/2";
+4k3/8/8/8/8/8/8/R3K3 b - - [1.0]
+4k3/8/8/8/8/8/7r/4K3 w - - 0-1
+4k3/8/8/8/8/8/8/4R1K1 b - - 1-0
+`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	positions, err := readTuningPositions(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the last position has black in check and is skipped
+	want := []float64{0.5, 1, 0}
+	if len(positions) != len(want) {
+		t.Fatalf("read %d positions, want %d", len(positions), len(want))
+	}
+	for i, p := range positions {
+		if p.result != want[i] {
+			t.Errorf("position %d result %v, want %v", i, p.result, want[i])
+		}
+	}
+
+	if err := os.WriteFile(path, []byte("4k3/8/8/8/8/8/8/4K3 w - -\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readTuningPositions(path); err == nil {
+		t.Error("position without a result was accepted")
+	}
+}
+
+func TestExpectedScore(t *testing.T) {
+	if got := expectedScore(0, 1); got != 0.5 {
+		t.Errorf("expected score of an even eval %v, want 0.5", got)
+	}
+	if up, down := expectedScore(200, 1), expectedScore(-200, 1); up <= 0.5 || up+down < 0.999 || up+down > 1.001 {
+		t.Errorf("expected scores %v and %v of opposite evals do not add up to 1", up, down)
+	}
+}
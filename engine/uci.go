@@ -49,6 +49,14 @@ func Uci(frGUI chan string, depth int) {
 			toEng <- "random"
 		case "eval":
 			toEng <- "eval"
+		case "eval json":
+			toEng <- "eval json"
+		case "result":
+			toEng <- "result"
+		case "resign":
+			toEng <- "resign"
+		case "undo", "redo":
+			toEng <- cmd
 		default:
 			if strings.HasPrefix(cmd, "position ") {
 				otherString := strings.TrimPrefix(cmd, "position ")
@@ -56,6 +64,18 @@ func Uci(frGUI chan string, depth int) {
 			} else if strings.HasPrefix(cmd, "move ") {
 				otherString := strings.TrimPrefix(cmd, "move ")
 				handleMove(toEng, otherString)
+			} else if strings.HasPrefix(cmd, "save ") || strings.HasPrefix(cmd, "load ") {
+				// store the game as PGN, or set up the first game of a PGN file
+				toEng <- cmd
+			} else if strings.HasPrefix(cmd, "undo ") || strings.HasPrefix(cmd, "redo ") {
+				// take back or replay a number of plies, e.g. "undo 2"
+				toEng <- cmd
+			} else if strings.HasPrefix(cmd, "setoption ") {
+				handleSetOption(toEng, strings.TrimPrefix(cmd, "setoption "))
+			} else if strings.HasPrefix(cmd, "see ") {
+				// debug: static exchange evaluation of a move, e.g. "see Pe4xd5"
+				otherString := strings.TrimPrefix(cmd, "see ")
+				handleSee(toEng, otherString)
 			}
 		case "quit":
 			quit = true
@@ -68,6 +88,10 @@ func handleMove(toEng chan string, otherString string) {
 	toEng <- "move " + otherString
 }
 
+func handleSee(toEng chan string, otherString string) {
+	toEng <- "see " + otherString
+}
+
 func handlePosition(toEng chan string, otherString string) {
 	toEng <- "fen " + otherString
 }
@@ -84,9 +108,33 @@ func handleDebugOn() {
 func handleUci() {
 	tell("id name Ashish")
 	tell("id author Ashish")
+	tell("option name EvalFile type string default <empty>")
+	tell("option name NNUEFile type string default <empty>")
+	tell("option name OwnBook type check default false")
+	tell("option name BookFile type string default <empty>")
+	tell("option name TablebaseFiles type string default <empty>")
 	tell("uciok")
 }
 
+// handleSetOption handles "setoption name <id> [value <x>]".
+func handleSetOption(toEng chan string, otherString string) {
+	name, value, _ := strings.Cut(strings.TrimPrefix(otherString, "name "), " value ")
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "evalfile":
+		toEng <- "evalfile " + strings.TrimSpace(value)
+	case "nnuefile":
+		toEng <- "nnuefile " + strings.TrimSpace(value)
+	case "ownbook":
+		toEng <- "ownbook " + strings.ToLower(strings.TrimSpace(value))
+	case "bookfile":
+		toEng <- "bookfile " + strings.TrimSpace(value)
+	case "tablebasefiles":
+		toEng <- "tablebasefiles " + strings.TrimSpace(value)
+	default:
+		tell("info string unknown option " + name)
+	}
+}
+
 func handleIsReady() {
 	tell("readyok")
 }
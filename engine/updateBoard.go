@@ -1,116 +1,53 @@
 package engine
 
-// updatePieceTypeOnBoard updates the position of a piece on the board based on its initial and final positions.
-// It takes a pointer to a Board struct, the initial position, final position, piece type, and a boolean indicating whether the piece is white.
-// If the piece is white, it updates the corresponding white piece bitboard based on the piece type.
-// If the piece is black, it updates the corresponding black piece bitboard based on the piece type.
-func (b *Board) movePiece(initPos, finalPos uint64, pieceType PieceType, isWhite bool) {
-
-	if isWhite {
-		b.whitePieces &= ^initPos
-		b.whitePieces |= finalPos
-		b.allPieces = b.whitePieces | b.blackPieces
-		if pieceType == Pawn {
-
-			b.whitePawns &= ^initPos
-			b.whitePawns |= finalPos
-
-		} else if pieceType == Knight {
-
-			b.whiteKnights &= ^initPos
-			b.whiteKnights |= finalPos
-
-		} else if pieceType == Bishop {
-
-			b.whiteBishops &= ^initPos
-			b.whiteBishops |= finalPos
-
-		} else if pieceType == Rook {
-			b.whiteRooks &= ^initPos
-			b.whiteRooks |= finalPos
-
-		} else if pieceType == Queen {
-
-			b.whiteQueens &= ^initPos
-			b.whiteQueens |= finalPos
-
-		} else if pieceType == King {
-
-			b.whiteKing &= ^initPos
-			b.whiteKing |= finalPos
-		}
+import "math/bits"
 
-	} else {
-		b.blackPieces &= ^initPos
-		b.blackPieces |= finalPos
-		b.allPieces = b.whitePieces | b.blackPieces
-		if pieceType == Pawn {
-
-			b.blackPawns &= ^initPos
-			b.blackPawns |= finalPos
-
-		} else if pieceType == Knight {
-
-			b.blackKnights &= ^initPos
-			b.blackKnights |= finalPos
-
-		} else if pieceType == Bishop {
-
-			b.blackBishops &= ^initPos
-			b.blackBishops |= finalPos
-
-		} else if pieceType == Rook {
-			b.blackRooks &= ^initPos
-			b.blackRooks |= finalPos
-
-		} else if pieceType == Queen {
-
-			b.blackQueens &= ^initPos
-			b.blackQueens |= finalPos
+// movePiece moves a piece of the given type and colour from initPos to finalPos,
+// keeping the bitboards, the mailbox, the hashes and the network accumulator in step.
+// An initPos of 0 puts a new piece on the board and a finalPos of 0 takes one off.
+func (b *Board) movePiece(initPos, finalPos uint64, pieceType PieceType, isWhite bool) {
+	idx := pieceIndex(pieceType)
+	if idx < 0 {
+		return
+	}
 
-		} else if pieceType == King {
+	b.hash ^= zobristKey(initPos, pieceType, isWhite) ^ zobristKey(finalPos, pieceType, isWhite)
+	if pieceType == Pawn {
+		b.pawnHash ^= zobristKey(initPos, pieceType, isWhite) ^ zobristKey(finalPos, pieceType, isWhite)
+	}
+	if b.acc.net != nil {
+		b.acc.net.update(&b.acc, initPos, finalPos, pieceType, isWhite)
+	}
+	if b.sums.version == evalParamsVersion {
+		b.sums.update(initPos, finalPos, pieceType, isWhite)
+	}
 
-			b.blackKing &= ^initPos
-			b.blackKing |= finalPos
-		}
+	colour := colourIndex(isWhite)
+	b.pieces[colour][idx] = b.pieces[colour][idx]&^initPos | finalPos
+	b.colours[colour] = b.colours[colour]&^initPos | finalPos
+	b.allPieces = b.colours[whiteIndex] | b.colours[blackIndex]
+	if initPos != 0 {
+		b.mailbox[bits.TrailingZeros64(initPos)] = 0
+	}
+	if finalPos != 0 {
+		b.mailbox[bits.TrailingZeros64(finalPos)] = pieceType
 	}
 }
 
 // makeMove applies a move to the chess board.
 // It updates the positions of the pieces on the board based on the initial and final positions provided.
-// If the moving piece is white, it updates the whitePieces bitboard accordingly.
-// If the moving piece is black, it updates the blackPieces bitboard accordingly.
-// If a piece of the opposite color is taken during the move, it updates the respective bitboard and piece type.
-// Finally, it updates the allPieces bitboard to reflect the new positions of all the pieces on the board.
+// If a piece of the opposite color is taken during the move, it is taken off the board first.
 
 // returns a boolean indicating whether a piece was captured during the move and the type of the captured piece.
 func (b *Board) makeMove(initPos, finalPos uint64, isWhite bool, pieceType PieceType) (bool, PieceType) {
-
-	if isWhite {
-		// If a black piece is taken
-		var wasPieceCaptured bool = (finalPos&b.blackPieces != 0)
-		var capturedPieceType PieceType = 0
-		if wasPieceCaptured {
-			b.blackPieces &= ^finalPos
-			capturedPieceType = b.getPieceType(finalPos)
-			b.movePiece(finalPos, 0, capturedPieceType, !isWhite)
-		}
-		b.movePiece(initPos, finalPos, pieceType, isWhite)
-		return wasPieceCaptured, capturedPieceType
-
-	} else {
-		// If a white piece is taken
-		var wasPieceCaptured bool = (finalPos&b.whitePieces != 0)
-		var capturedPieceType PieceType = 0
-		if wasPieceCaptured {
-			b.whitePieces &= ^finalPos
-			capturedPieceType = b.getPieceType(finalPos)
-			b.movePiece(finalPos, 0, capturedPieceType, !isWhite)
-		}
-		b.movePiece(initPos, finalPos, pieceType, isWhite)
-		return wasPieceCaptured, capturedPieceType
+	var wasPieceCaptured bool = finalPos&b.colours[colourIndex(!isWhite)] != 0
+	var capturedPieceType PieceType = 0
+	if wasPieceCaptured {
+		capturedPieceType = b.getPieceType(finalPos)
+		b.movePiece(finalPos, 0, capturedPieceType, !isWhite)
 	}
-
+	b.movePiece(initPos, finalPos, pieceType, isWhite)
+	return wasPieceCaptured, capturedPieceType
 }
 
 // unmakeMove takes the initial position, final position, and other parameters of a move and reverts the board state to the previous state.
@@ -121,16 +58,80 @@ func (b *Board) makeMove(initPos, finalPos uint64, isWhite bool, pieceType Piece
 // - isWhite: A boolean indicating whether the moving piece is white.
 // - capturedPieceType: The type of the captured piece, if any.
 func (b *Board) unmakeMove(initPos, finalPos uint64, isWhite, wasPieceCaptured bool, capturedPieceType PieceType) {
-	if isWhite {
-		b.movePiece(finalPos, initPos, b.getPieceType(finalPos), isWhite)
-		if wasPieceCaptured {
-			b.movePiece(0, finalPos, capturedPieceType, !isWhite)
-		}
-	} else {
-		b.movePiece(finalPos, initPos, b.getPieceType(finalPos), isWhite)
-		if wasPieceCaptured {
-			b.movePiece(0, finalPos, capturedPieceType, !isWhite)
+	b.movePiece(finalPos, initPos, b.getPieceType(finalPos), isWhite)
+	if wasPieceCaptured {
+		b.movePiece(0, finalPos, capturedPieceType, !isWhite)
+	}
+}
+
+// moveUndo is what doMove changes that cannot be worked out again from the move.
+type moveUndo struct {
+	captured PieceType
+	castling uint8
+	epSquare uint64
+}
+
+// doMove plays a move for a side, including castling, en passant and promotions, and
+// updates the castling rights and en passant square. The returned moveUndo takes the
+// board back with undoMove.
+func (b *Board) doMove(m Move, isWhite bool) moveUndo {
+	undo := moveUndo{captured: NoPiece, castling: b.castling, epSquare: b.epSquare}
+	from, to := m.from(), m.to()
+	pieceType := b.getPieceType(from)
+
+	if m.isEnPassant() {
+		b.movePiece(enPassantVictim(to, isWhite), 0, Pawn, !isWhite)
+		undo.captured = Pawn
+	}
+	if wasPieceCaptured, capturedPieceType := b.makeMove(from, to, isWhite, pieceType); wasPieceCaptured {
+		undo.captured = capturedPieceType
+	}
+	if promotion := m.promotion(); promotion != NoPiece {
+		b.movePiece(to, 0, Pawn, isWhite)
+		b.movePiece(0, to, promotion, isWhite)
+	}
+	if m.isCastle() {
+		_, rookFrom, rookTo := castlingRookSquares(from, to > from)
+		b.movePiece(rookFrom, rookTo, Rook, isWhite)
+	}
+
+	b.updateCastling(from, to)
+	b.epSquare = 0
+	if m.isDoublePush() {
+		if isWhite {
+			b.epSquare = from << 8
+		} else {
+			b.epSquare = from >> 8
 		}
 	}
-	b.allPieces = b.whitePieces | b.blackPieces
+	return undo
+}
+
+// undoMove takes back a move made by doMove.
+func (b *Board) undoMove(m Move, isWhite bool, undo moveUndo) {
+	from, to := m.from(), m.to()
+	if m.isCastle() {
+		_, rookFrom, rookTo := castlingRookSquares(from, to > from)
+		b.movePiece(rookTo, rookFrom, Rook, isWhite)
+	}
+	if promotion := m.promotion(); promotion != NoPiece {
+		b.movePiece(to, 0, promotion, isWhite)
+		b.movePiece(0, to, Pawn, isWhite)
+	}
+	b.movePiece(to, from, b.getPieceType(to), isWhite)
+	if m.isEnPassant() {
+		b.movePiece(0, enPassantVictim(to, isWhite), Pawn, !isWhite)
+	} else if undo.captured != NoPiece {
+		b.movePiece(0, to, undo.captured, !isWhite)
+	}
+	b.castling, b.epSquare = undo.castling, undo.epSquare
+}
+
+// enPassantVictim returns the square of the pawn taken when a pawn of the given side
+// takes en passant onto to.
+func enPassantVictim(to uint64, isWhite bool) uint64 {
+	if isWhite {
+		return to >> 8
+	}
+	return to << 8
 }
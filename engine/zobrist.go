@@ -0,0 +1,102 @@
+package engine
+
+import (
+	"math/bits"
+	"math/rand"
+)
+
+// zobristPieces holds a random key for every colour, piece type and square.
+// A position's hash is the xor of the keys of all pieces on the board, so it can be
+// updated incrementally whenever a piece is added to or removed from a square.
+var zobristPieces [2][6][64]uint64
+
+// zobristWhiteToMove is mixed into the hash when it is white's turn, so that the same
+// placement with a different side to move counts as a different position.
+var zobristWhiteToMove uint64
+
+// zobristCastling and zobristEnPassant key the castling rights and the file of the en
+// passant square, which also tell positions with the same placement apart.
+var (
+	zobristCastling  [16]uint64
+	zobristEnPassant [8]uint64
+)
+
+func init() {
+	// fixed seed so hashes are reproducible between runs
+	r := rand.New(rand.NewSource(0x63686b6d38))
+	for colour := 0; colour < 2; colour++ {
+		for piece := 0; piece < 6; piece++ {
+			for sq := 0; sq < 64; sq++ {
+				zobristPieces[colour][piece][sq] = r.Uint64()
+			}
+		}
+	}
+	zobristWhiteToMove = r.Uint64()
+	// rights are keyed as a whole, the empty set with zero
+	for rights := 1; rights < 16; rights++ {
+		zobristCastling[rights] = r.Uint64()
+	}
+	for file := 0; file < 8; file++ {
+		zobristEnPassant[file] = r.Uint64()
+	}
+}
+
+// pieceIndex maps a piece type to its index in Board.pieces and the zobrist tables,
+// or -1 when it is not a piece.
+func pieceIndex(pieceType PieceType) int {
+	switch pieceType {
+	case Pawn:
+		return pawnIndex
+	case Knight:
+		return knightIndex
+	case Bishop:
+		return bishopIndex
+	case Rook:
+		return rookIndex
+	case Queen:
+		return queenIndex
+	case King:
+		return kingIndex
+	}
+	return -1
+}
+
+// zobristKey returns the key of a piece standing on the single square set in pos.
+// An empty pos or an unknown piece type has no key.
+func zobristKey(pos uint64, pieceType PieceType, isWhite bool) uint64 {
+	idx := pieceIndex(pieceType)
+	if pos == 0 || idx < 0 {
+		return 0
+	}
+	return zobristPieces[colourIndex(isWhite)][idx][bits.TrailingZeros64(pos)]
+}
+
+// computeHash builds the hash of the board, and of its pawns alone, from scratch.
+func (b *Board) computeHash() (uint64, uint64) {
+	var hash, pawnHash uint64
+	for colour := range b.pieces {
+		for idx, pieces := range b.pieces[colour] {
+			for ; pieces != 0; pieces &= pieces - 1 {
+				key := zobristPieces[colour][idx][bits.TrailingZeros64(pieces)]
+				hash ^= key
+				if idx == pawnIndex {
+					pawnHash ^= key
+				}
+			}
+		}
+	}
+	return hash, pawnHash
+}
+
+// positionKey returns the hash identifying the position with the given side to move,
+// its castling rights and, when a pawn can take on it, its en passant square.
+func (b *Board) positionKey(whiteToMove bool) uint64 {
+	key := b.hash ^ zobristCastling[b.castling]
+	if b.canTakeEnPassant(whiteToMove) {
+		key ^= zobristEnPassant[squareFile(bits.TrailingZeros64(b.epSquare))]
+	}
+	if whiteToMove {
+		key ^= zobristWhiteToMove
+	}
+	return key
+}